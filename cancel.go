@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+
+	"github.com/karlseguin/msql/driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// While a statement is running, Ctrl-C cancels it rather than killing msql.
+// The server is asked to stop the query (over a side connection) while we keep
+// reading, but discard, whatever it sends until the reply is complete. This
+// leaves the connection in a usable state.
+type canceller struct {
+	out       io.Writer
+	cancelled int32
+	signals   chan os.Signal
+	done      chan struct{}
+
+	// of the connection running the statement, captured up front since a
+	// reconnect can change them while we're stopping it
+	config    driver.Config
+	sessionId int
+
+	// the listener and the stops in progress, which Stop waits for so that a
+	// late stop can't hit the next statement
+	running sync.WaitGroup
+}
+
+func watchInterrupt(context *Context) *canceller {
+	c := &canceller{
+		out:       context.out,
+		signals:   make(chan os.Signal, 1),
+		done:      make(chan struct{}),
+		config:    context.config,
+		sessionId: context.sessionId,
+	}
+	signal.Notify(c.signals, os.Interrupt)
+	c.running.Add(1)
+	go c.listen()
+	return c
+}
+
+func (c *canceller) listen() {
+	defer c.running.Done()
+	for {
+		select {
+		case <-c.signals:
+			if atomic.SwapInt32(&c.cancelled, 1) == 0 {
+				// not to out, which is still being rendered to (by another
+				// goroutine) and might be a file
+				io.WriteString(os.Stderr, "\ncancelling...\n")
+			}
+			// every Ctrl-C tries again, in case the query wasn't running yet
+			c.stop()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Like Ctrl-C, but silent (used when the pager is quit before the result has
+// been fully read)
+func (c *canceller) Cancel() {
+	if atomic.SwapInt32(&c.cancelled, 1) == 0 {
+		c.stop()
+	}
+}
+
+func (c *canceller) Cancelled() bool {
+	return atomic.LoadInt32(&c.cancelled) == 1
+}
+
+// Once cancelled, output is discarded
+func (c *canceller) Write(data []byte) (int, error) {
+	if c.Cancelled() {
+		return len(data), nil
+	}
+	return c.out.Write(data)
+}
+
+// Called once the reply has been read, waits for any stop still in progress
+func (c *canceller) Stop() {
+	signal.Stop(c.signals)
+	close(c.done)
+	c.running.Wait()
+}
+
+func (c *canceller) stop() {
+	c.running.Add(1)
+	go func() {
+		defer c.running.Done()
+		stopRunning(c.config, c.sessionId)
+	}()
+}
+
+// Stops the running query(ies) of the session using a separate connection,
+// since the session's is busy receiving the result.
+func stopRunning(config driver.Config, sessionId int) {
+	if sessionId == -1 {
+		log.Error("this server doesn't support cancelling queries, waiting for it to finish")
+		return
+	}
+
+	side, err := driver.Open(config)
+	if err != nil {
+		log.WithFields(log.Fields{"context": "cancel: connect"}).Error(err)
+		return
+	}
+	defer side.Close()
+
+	tags, err := side.QueryRows(fmt.Sprintf("select tag from sys.queue() where sessionid = %d and status = 'running'", sessionId))
+	if err != nil {
+		log.WithFields(log.Fields{"context": "cancel: queue"}).Error(err)
+		return
+	}
+
+	for _, tag := range tags {
+		if _, err := side.QueryRows("call sys.stop(" + tag[0] + ")"); err != nil {
+			log.WithFields(log.Fields{"context": "cancel: stop", "tag": tag[0]}).Error(err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karlseguin/msql/driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// how often the catalog is reloaded (it's also reloaded after statements which
+// look like they change it)
+const CATALOG_REFRESH = time.Minute
+
+// The names used by tab completion. Loading them can take a while on a large
+// database, so it's done in the background, on its own connection, and
+// completion uses whatever was last loaded.
+type catalog struct {
+	sync.RWMutex
+	config  driver.Config
+	refresh chan struct{}
+
+	schemas   []string
+	tables    map[string][]string // by schema, includes views
+	columns   map[string][]string // by schema.table
+	functions []string
+}
+
+func newCatalog(config driver.Config) *catalog {
+	c := &catalog{
+		config:  config,
+		refresh: make(chan struct{}, 1),
+	}
+	go c.run()
+	return c
+}
+
+// Reloads the catalog (without waiting for it to be reloaded)
+func (c *catalog) Refresh() {
+	select {
+	case c.refresh <- struct{}{}:
+	default:
+		// a refresh is already pending
+	}
+}
+
+func (c *catalog) run() {
+	var conn driver.Conn
+	connected := false
+	for {
+		if !connected {
+			var err error
+			if conn, err = driver.Open(c.config); err != nil {
+				log.WithFields(log.Fields{"context": "catalog connect"}).Info(err)
+			} else {
+				connected = true
+			}
+		}
+		if connected {
+			if err := c.load(conn); err != nil {
+				log.WithFields(log.Fields{"context": "catalog load"}).Info(err)
+				// we'll connect again next time
+				conn.Close()
+				connected = false
+			}
+		}
+
+		select {
+		case <-c.refresh:
+		case <-time.After(CATALOG_REFRESH):
+		}
+	}
+}
+
+func (c *catalog) load(conn driver.Conn) error {
+	schemaRows, err := conn.QueryRows("select name from sys.schemas")
+	if err != nil {
+		return err
+	}
+	tableRows, err := conn.QueryRows("select s.name, t.name from sys.tables t join sys.schemas s on t.schema_id = s.id")
+	if err != nil {
+		return err
+	}
+	columnRows, err := conn.QueryRows("select s.name, t.name, c.name from sys._columns c join sys.tables t on c.table_id = t.id join sys.schemas s on t.schema_id = s.id order by c.number")
+	if err != nil {
+		return err
+	}
+	functionRows, err := conn.QueryRows("select distinct name from sys.functions")
+	if err != nil {
+		return err
+	}
+
+	schemas := make([]string, 0, len(schemaRows))
+	for _, row := range schemaRows {
+		schemas = append(schemas, row[0])
+	}
+	tables := make(map[string][]string)
+	for _, row := range tableRows {
+		tables[row[0]] = append(tables[row[0]], row[1])
+	}
+	columns := make(map[string][]string)
+	for _, row := range columnRows {
+		key := row[0] + "." + row[1]
+		columns[key] = append(columns[key], row[2])
+	}
+	functions := make([]string, 0, len(functionRows))
+	for _, row := range functionRows {
+		functions = append(functions, row[0])
+	}
+	sort.Strings(schemas)
+	sort.Strings(functions)
+
+	c.Lock()
+	c.schemas = schemas
+	c.tables = tables
+	c.columns = columns
+	c.functions = functions
+	c.Unlock()
+	return nil
+}
+
+func (c *catalog) Schemas() []string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.schemas
+}
+
+func (c *catalog) Tables(schema string) []string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.tables[schema]
+}
+
+func (c *catalog) Functions() []string {
+	c.RLock()
+	defer c.RUnlock()
+	return c.functions
+}
+
+// The table is looked for in each schema (in order) when it isn't qualified
+func (c *catalog) Columns(table string, schemas ...string) []string {
+	c.RLock()
+	defer c.RUnlock()
+	if dot := strings.IndexByte(table, '.'); dot != -1 {
+		return c.columns[table]
+	}
+	for _, schema := range schemas {
+		if columns, ok := c.columns[schema+"."+table]; ok {
+			return columns
+		}
+	}
+	return nil
+}
+
+// Statements which change the catalog. A false positive only costs us a
+// reload.
+func changesCatalog(statement string) bool {
+	fields := strings.Fields(strings.ToLower(statement))
+	if len(fields) == 0 {
+		return false
+	}
+	switch fields[0] {
+	case "create", "drop", "alter", "rename":
+		return true
+	}
+	return false
+}
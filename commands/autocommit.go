@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type AutoCommit struct {
+}
+
+func (cmd AutoCommit) Execute(context Context, args string) {
+	var on bool
+	switch strings.ToLower(args) {
+	case "on":
+		on = true
+	case "off":
+		on = false
+	default:
+		log.Error("valid options for \\autocommit are: 'on' or 'off'")
+		return
+	}
+
+	if err := context.AutoCommit(on); err != nil {
+		log.WithFields(log.Fields{"context": "autocommit"}).Error(err)
+		return
+	}
+	if on {
+		context.WriteString("Auto-commit is on\n")
+	} else {
+		context.WriteString("Auto-commit is off\n")
+	}
+}
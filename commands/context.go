@@ -1,12 +1,25 @@
 package commands
 
-import "github.com/karlseguin/msql/driver"
+import (
+	"github.com/karlseguin/msql/driver"
+	"github.com/karlseguin/msql/outputs"
+)
 
 type Context interface {
 	WriteString(string)
-	Format(string)
+	Format(string) error
+	SetFormatter(format outputs.Format, formatter outputs.Formatter)
+	OutputFormat() outputs.Format
+	Display() *outputs.Display
 	Timing(bool)
+	AutoCommit(bool) error
 	Query(string)
+	Exec(sql string, stmt *driver.Stmt, args []interface{})
+	QueryTo(sql string, target string) error
+	LastQuery() string
+	Output(target string) error
+	Tee(file string) error
 	Schema() string
 	Conn() driver.Conn
+	Prepared() map[string]*driver.Stmt
 }
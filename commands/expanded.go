@@ -13,13 +13,21 @@ func (cmd Expanded) Execute(context Context, args string) {
 	switch strings.ToLower(args) {
 	case "on":
 		context.Format("expanded")
+		context.Display().ExpandedAuto = false
 		context.WriteString("Expanded display is on\n")
 		return
 	case "off":
 		context.Format("sql")
+		context.Display().ExpandedAuto = false
 		context.WriteString("Expanded display is off\n")
 		return
+	case "auto":
+		// the sql format switches to expanded when the table doesn't fit
+		context.Format("sql")
+		context.Display().ExpandedAuto = true
+		context.WriteString("Expanded display is used automatically\n")
+		return
 	default:
-		log.Error("valid options for \\x are: 'on' or 'off'")
+		log.Error("valid options for \\x are: 'on', 'off' or 'auto'")
 	}
 }
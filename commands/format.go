@@ -1,8 +1,10 @@
 package commands
 
 import (
+	"fmt"
 	"strings"
 
+	"github.com/karlseguin/msql/outputs"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -10,24 +12,52 @@ type Format struct {
 }
 
 func (cmd Format) Execute(context Context, args string) {
-	switch strings.ToLower(args) {
-	case "raw":
-		context.Format("raw")
-		context.WriteString("Raw display is on\n")
+	format, err := ApplyFormat(context, args)
+	if err != nil {
+		log.Error(err)
 		return
-	case "sql":
-		context.Format("sql")
-		context.WriteString("SQL display is on\n")
-		return
-	case "expanded":
-		context.Format("expanded")
-		context.WriteString("Expanded display is on\n")
-		return
-	case "trash":
-		context.Format("trash")
-		context.WriteString("Trash display is on\n")
-		return
-	default:
-		log.Error("valid formats for \\f are: 'raw', 'sql' and 'expanded'")
 	}
+	context.WriteString(format.Label + " display is on\n")
+}
+
+// FORMAT [OPTION=VALUE ...]
+// A value without a name is the format's Argument (e.g. insert TABLE)
+// Shared with the --format command line flag
+func ApplyFormat(context Context, args string) (outputs.Format, error) {
+	parts := strings.Fields(args)
+	if len(parts) == 0 {
+		return outputs.Format{}, formatError()
+	}
+
+	format, ok := outputs.Lookup(strings.ToLower(parts[0]))
+	if !ok {
+		return outputs.Format{}, formatError()
+	}
+
+	// the options are all set before the format is switched to, so that an
+	// invalid one leaves the current format as it was
+	formatter := format.New()
+	for _, option := range parts[1:] {
+		kv := strings.SplitN(option, "=", 2)
+		if len(kv) == 1 && format.Argument != "" {
+			kv = []string{format.Argument, option}
+		}
+		if len(kv) != 2 {
+			return outputs.Format{}, fmt.Errorf("invalid format option '%s', expected OPTION=VALUE", option)
+		}
+		if err := outputs.SetOption(format, formatter, strings.ToLower(kv[0]), kv[1]); err != nil {
+			return outputs.Format{}, err
+		}
+	}
+	context.SetFormatter(format, formatter)
+	return format, nil
+}
+
+func formatError() error {
+	names := outputs.Names()
+	for i, name := range names {
+		names[i] = "'" + name + "'"
+	}
+	last := len(names) - 1
+	return fmt.Errorf("valid formats for \\f are: %s and %s", strings.Join(names[:last], ", "), names[last])
 }
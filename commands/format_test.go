@@ -0,0 +1,37 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/karlseguin/msql/outputs"
+)
+
+// Only what ApplyFormat uses
+type formatContext struct {
+	Context
+	format outputs.Format
+}
+
+func (c *formatContext) SetFormatter(format outputs.Format, formatter outputs.Formatter) {
+	c.format = format
+}
+
+func Test_ApplyFormat(t *testing.T) {
+	context := &formatContext{}
+	if _, err := ApplyFormat(context, "csv delimiter=; header=off"); err != nil {
+		t.Fatal(err)
+	}
+	if context.format.Name != "csv" {
+		t.Errorf("expected csv, got %s", context.format.Name)
+	}
+
+	// an invalid option leaves the format as it was
+	for _, args := range []string{"tsv delimter=;", "tsv delimiter=;;", "tsv header"} {
+		if _, err := ApplyFormat(context, args); err == nil {
+			t.Errorf("%s: expected an error", args)
+		}
+		if context.format.Name != "csv" {
+			t.Errorf("%s: expected csv to still be the format, got %s", args, context.format.Name)
+		}
+	}
+}
@@ -1,5 +1,12 @@
 package commands
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/karlseguin/msql/outputs"
+)
+
 type Help struct {
 }
 
@@ -9,8 +16,44 @@ func (cmd Help) Execute(context Context, input string) {
 \? - Outputs this help screen
 \h - Alias for \?
 
-\f FORMAT - sets the output format to one of: 'raw', 'expanded' or 'sql'
-\x on|off - turns expanded format on or off (for compatibility with psql)
+`)
+	helpFormats(context)
+	context.WriteString(`\x on|off|auto - turns expanded format on or off, auto uses it when a table is wider than the terminal
+\pset [wrap|truncate [on|off]] - wraps or truncates long values so that tables fit the terminal
+\pset expanded on|off|auto - same as \x
+\pset pager on|off|always - pages output that doesn't fit the terminal ($PAGER, defaults to less -S)
+\pset null TEXT - the text shown for NULL (defaults to NULL)
+\pset border ascii|none|unicode - the lines drawn around and between the columns of the sql format
+\pset header|footer|types on|off - shows the column names, the row count and timing, or the column types
+\pset footerFormat TEXT - the footer's template, e.g. "${count} rows in ${clk}" (see the readme)
+\pset numericLocale on|off - formats numbers with the separators of the locale
 \timing on|off - turns timing information on or off
+\autocommit on|off - turns auto-commit on or off
+
+\o [FILE|"|"COMMAND] - sends query results to FILE or to COMMAND's input, without an argument back to stdout
+\tee [FILE] - also appends query results to FILE, without an argument stops
+\g [FILE|"|"COMMAND] - ends a query (instead of ;) and sends only its results to FILE or COMMAND, on its own line re-runs the last query
+
+\prepare [NAME SQL] - prepares SQL (using ? for parameters), or lists the prepared statements
+\exec NAME [ARGS] - executes a prepared statement, ARGS are comma-separated SQL literals
+\deallocate NAME - deallocates a prepared statement
 `)
 }
+
+// The formats come from the outputs registry, which can include formats that
+// msql doesn't know about
+func helpFormats(context Context) {
+	context.WriteString("\\f FORMAT [OPTION=VALUE ...] - sets the output format to one of:\n")
+	for _, format := range outputs.Formats() {
+		context.WriteString(fmt.Sprintf("  %-9s %s\n", format.Name, format.Description))
+		if len(format.Options) == 0 {
+			continue
+		}
+		names := make([]string, len(format.Options))
+		for i, option := range format.Options {
+			names[i] = option.Name
+		}
+		context.WriteString(fmt.Sprintf("  %-9s options: %s\n", "", strings.Join(names, ", ")))
+	}
+	context.WriteString("  e.g. \\f csv delimiter=; null=NULL header=off  or  \\f insert users batch=100\n")
+}
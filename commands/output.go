@@ -0,0 +1,57 @@
+package commands
+
+import (
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// \o [FILE | |COMMAND], without a target, results go back to stdout
+type Output struct {
+}
+
+func (cmd Output) Execute(context Context, args string) {
+	target := strings.TrimSpace(args)
+	if err := context.Output(target); err != nil {
+		log.WithFields(log.Fields{"context": "\\o"}).Error(err)
+		return
+	}
+	if target == "" {
+		context.WriteString("Output goes to stdout\n")
+	} else {
+		context.WriteString("Output goes to " + target + "\n")
+	}
+}
+
+// \tee [FILE], without a file, stops copying results to it
+type Tee struct {
+}
+
+func (cmd Tee) Execute(context Context, args string) {
+	file := strings.TrimSpace(args)
+	if err := context.Tee(file); err != nil {
+		log.WithFields(log.Fields{"context": "\\tee"}).Error(err)
+		return
+	}
+	if file == "" {
+		context.WriteString("Tee is off\n")
+	} else {
+		context.WriteString("Output is also written to " + file + "\n")
+	}
+}
+
+// \g [FILE | |COMMAND] on its own line re-runs the last query. Ending a query
+// with \g (instead of ;) is handled when the statement is collected.
+type Go struct {
+}
+
+func (cmd Go) Execute(context Context, args string) {
+	sql := context.LastQuery()
+	if sql == "" {
+		log.Error("there's no query to execute")
+		return
+	}
+	if err := context.QueryTo(sql, strings.TrimSpace(args)); err != nil {
+		log.WithFields(log.Fields{"context": "\\g"}).Error(err)
+	}
+}
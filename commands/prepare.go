@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/karlseguin/msql/driver"
+	"github.com/karlseguin/msql/lexer"
+	log "github.com/sirupsen/logrus"
+)
+
+type Prepare struct {
+}
+
+// \prepare NAME SQL
+// Without arguments, lists the prepared statements
+func (cmd Prepare) Execute(context Context, args string) {
+	prepared := context.Prepared()
+	if args == "" {
+		names := make([]string, 0, len(prepared))
+		for name := range prepared {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			context.WriteString(fmt.Sprintf("%s(%s)\n", name, paramTypes(prepared[name].Params())))
+		}
+		return
+	}
+
+	parts := strings.SplitN(args, " ", 2)
+	if len(parts) != 2 {
+		log.Error("usage: \\prepare NAME SQL")
+		return
+	}
+
+	name := parts[0]
+	sql := strings.TrimSuffix(strings.TrimSpace(parts[1]), ";")
+	stmt, err := context.Conn().Prepare(sql)
+	if err != nil {
+		log.WithFields(log.Fields{"context": "prepare", "name": name}).Error(err)
+		return
+	}
+
+	if existing, ok := prepared[name]; ok {
+		if err := existing.Close(); err != nil {
+			log.WithFields(log.Fields{"context": "prepare: deallocate", "name": name}).Error(err)
+		}
+	}
+	prepared[name] = stmt
+	context.WriteString(fmt.Sprintf("OK, use: \\exec %s %s\n", name, paramTypes(stmt.Params())))
+}
+
+type Exec struct {
+}
+
+// \exec NAME ARG1, ARG2, ...
+// The arguments are SQL literals, converted to the statement's parameters
+// (see execArgs)
+func (cmd Exec) Execute(context Context, args string) {
+	parts := strings.SplitN(args, " ", 2)
+	name := parts[0]
+	if name == "" {
+		log.Error("usage: \\exec NAME [ARGS]")
+		return
+	}
+
+	stmt, ok := context.Prepared()[name]
+	if !ok {
+		log.Errorf("unknown prepared statement: %s", name)
+		return
+	}
+
+	literals := ""
+	if len(parts) == 2 {
+		literals = strings.TrimSuffix(strings.TrimSpace(parts[1]), ";")
+	}
+	values, err := execArgs(literals)
+	if err != nil {
+		log.WithFields(log.Fields{"context": "exec", "name": name}).Error(err)
+		return
+	}
+	context.Exec(fmt.Sprintf("exec %s(%s);", stmt.Id(), literals), stmt, values)
+}
+
+// Turns the comma separated literals of \exec into values for Stmt.Exec:
+// NULL, true and false, numbers and strings. A string can be prefixed by its
+// type (e.g. date '2021-03-04'), the parameter's type is used either way.
+func execArgs(literals string) ([]interface{}, error) {
+	var tokens []lexer.Token
+	for _, token := range lexer.Tokenize(literals) {
+		if !token.Insignificant() {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+
+	var values []interface{}
+	for {
+		end := 0
+		for end < len(tokens) && !(tokens[end].Kind == lexer.OPERATOR && tokens[end].Text == ",") {
+			end += 1
+		}
+		value, err := execArg(tokens[:end])
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+		if end == len(tokens) {
+			return values, nil
+		}
+		tokens = tokens[end+1:]
+	}
+}
+
+func execArg(tokens []lexer.Token) (interface{}, error) {
+	switch {
+	case len(tokens) == 1 && tokens[0].Is("null"):
+		return nil, nil
+	case len(tokens) == 1 && tokens[0].Is("true"):
+		return true, nil
+	case len(tokens) == 1 && tokens[0].Is("false"):
+		return false, nil
+	case len(tokens) == 1 && tokens[0].Kind == lexer.NUMBER:
+		return tokens[0].Text, nil
+	case len(tokens) == 2 && tokens[0].Kind == lexer.OPERATOR && tokens[0].Text == "-" && tokens[1].Kind == lexer.NUMBER:
+		return "-" + tokens[1].Text, nil
+	case len(tokens) == 1 && tokens[0].Kind == lexer.STRING && !tokens[0].Unterminated:
+		return unquote(tokens[0].Text), nil
+	case len(tokens) == 2 && tokens[0].Kind == lexer.WORD && tokens[1].Kind == lexer.STRING && !tokens[1].Unterminated:
+		return unquote(tokens[1].Text), nil
+	}
+
+	var text []string
+	for _, token := range tokens {
+		text = append(text, token.Text)
+	}
+	return nil, fmt.Errorf("expected a literal (e.g. 1, 'text', date '2021-03-04' or NULL), got: %s", strings.Join(text, " "))
+}
+
+// The value of a '...', E'...', R'...' or X'...' string. Like the server,
+// backslash escapes apply unless the string is raw (or hex).
+func unquote(s string) string {
+	escapes := true
+	if s[0] != '\'' {
+		escapes = s[0] == 'e' || s[0] == 'E'
+		s = s[1:]
+	}
+	s = s[1 : len(s)-1]
+
+	var value strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i += 1
+		case c == '\\' && escapes && i+1 < len(s):
+			i += 1
+			switch c = s[i]; c {
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'r':
+				c = '\r'
+			}
+		}
+		value.WriteByte(c)
+	}
+	return value.String()
+}
+
+type Deallocate struct {
+}
+
+func (cmd Deallocate) Execute(context Context, args string) {
+	name := strings.TrimSpace(args)
+	prepared := context.Prepared()
+	stmt, ok := prepared[name]
+	if !ok {
+		log.Errorf("unknown prepared statement: %s", name)
+		return
+	}
+	delete(prepared, name)
+	if err := stmt.Close(); err != nil {
+		log.WithFields(log.Fields{"context": "deallocate", "name": name}).Error(err)
+	}
+}
+
+func paramTypes(params []driver.Field) string {
+	types := make([]string, len(params))
+	for i, param := range params {
+		types[i] = param.Type
+	}
+	return strings.Join(types, ", ")
+}
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_ExecArgs(t *testing.T) {
+	cases := []struct {
+		literals string
+		want     []interface{}
+	}{
+		{"", nil},
+		{"1", []interface{}{"1"}},
+		{"-1.5, 2e3", []interface{}{"-1.5", "2e3"}},
+		{"NULL, null, true, FALSE", []interface{}{nil, nil, true, false}},
+		{"'leto', 'it''s', 'a\\tb', ''", []interface{}{"leto", "it's", "a\tb", ""}},
+		{"E'a\\nb', R'a\\nb', X'00ff'", []interface{}{"a\nb", `a\nb`, "00ff"}},
+		{"'a, b', 'NULL'", []interface{}{"a, b", "NULL"}},
+		{"date '2021-03-04', timestamp '2021-03-04 13:14:15'", []interface{}{"2021-03-04", "2021-03-04 13:14:15"}},
+		{" 1 ,\t'x' ", []interface{}{"1", "x"}},
+	}
+
+	for _, c := range cases {
+		actual, err := execArgs(c.literals)
+		if err != nil {
+			t.Errorf("%s: %v", c.literals, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, c.want) {
+			t.Errorf("%s:\n  got:  %#v\n  want: %#v", c.literals, actual, c.want)
+		}
+	}
+}
+
+func Test_ExecArgs_Invalid(t *testing.T) {
+	for _, literals := range []string{
+		"1); drop table users; --",
+		"id",
+		"1 + 1",
+		"'unterminated",
+		"1,,2",
+		"1,",
+		"(select 1)",
+	} {
+		if actual, err := execArgs(literals); err == nil {
+			t.Errorf("%s: expected an error, got %#v", literals, actual)
+		}
+	}
+}
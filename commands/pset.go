@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/karlseguin/msql/outputs"
+	log "github.com/sirupsen/logrus"
+)
+
+type Pset struct {
+}
+
+// \pset [NAME [VALUE]], without a name, lists the settings
+func (cmd Pset) Execute(context Context, args string) {
+	parts := strings.Fields(args)
+	if len(parts) == 0 {
+		listPset(context)
+		return
+	}
+
+	name := parts[0]
+	value := ""
+	if len(parts) > 1 {
+		value = strings.ToLower(parts[1])
+	}
+
+	display := context.Display()
+	switch strings.ToLower(name) {
+	case "wrap":
+		setOverflow(context, display, outputs.OVERFLOW_WRAP, "Wrapping", value)
+	case "truncate":
+		setOverflow(context, display, outputs.OVERFLOW_TRUNCATE, "Truncating", value)
+	case "expanded":
+		Expanded{}.Execute(context, value)
+	default:
+		// the rest of the line, as-is, since null and footerFormat can have
+		// spaces (and care about case)
+		value = strings.TrimSpace(strings.TrimSpace(args)[len(name):])
+		if len(value) > 1 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+		if err := display.Set(name, value); err != nil {
+			if err == outputs.ErrUnknownSetting {
+				log.Error("valid settings for \\pset are: 'wrap', 'truncate', 'expanded', '" + strings.Join(outputs.DisplaySettings, "', '") + "'")
+			} else {
+				log.Error(err)
+			}
+			return
+		}
+		context.WriteString(fmt.Sprintf("%s is %s\n", name, display.Get(name)))
+	}
+}
+
+// wrap and truncate are mutually exclusive, turning one on turns the other off
+func setOverflow(context Context, display *outputs.Display, overflow int, label string, value string) {
+	switch value {
+	case "", "on":
+		display.Overflow = overflow
+		context.WriteString(label + " long values to fit the terminal is on\n")
+	case "off":
+		if display.Overflow == overflow {
+			display.Overflow = outputs.OVERFLOW_NONE
+		}
+		context.WriteString(label + " long values to fit the terminal is off\n")
+	default:
+		log.Error("valid values are: 'on' or 'off'")
+	}
+}
+
+func listPset(context Context) {
+	display := context.Display()
+	onOff := func(on bool) string {
+		if on {
+			return "on"
+		}
+		return "off"
+	}
+
+	expanded := "off"
+	if context.OutputFormat().Name == "expanded" {
+		expanded = "on"
+	} else if display.ExpandedAuto {
+		expanded = "auto"
+	}
+
+	context.WriteString(fmt.Sprintf("%-14s %s\n", "wrap", onOff(display.Overflow == outputs.OVERFLOW_WRAP)))
+	context.WriteString(fmt.Sprintf("%-14s %s\n", "truncate", onOff(display.Overflow == outputs.OVERFLOW_TRUNCATE)))
+	context.WriteString(fmt.Sprintf("%-14s %s\n", "expanded", expanded))
+	for _, name := range outputs.DisplaySettings {
+		context.WriteString(fmt.Sprintf("%-14s %s\n", name, display.Get(name)))
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/karlseguin/msql/lexer"
+	"github.com/karlseguin/msql/outputs"
+	"github.com/knz/go-libedit"
+)
+
+var sqlKeywords = []string{
+	"add", "all", "alter", "and", "as", "asc", "begin", "between", "by", "call",
+	"case", "cast", "check", "column", "comment", "commit", "constraint", "copy",
+	"create", "cross", "current_date", "current_schema", "current_time",
+	"current_timestamp", "current_user", "declare", "default", "delete", "desc",
+	"distinct", "drop", "else", "end", "except", "exists", "explain", "false",
+	"first", "foreign", "from", "full", "function", "grant", "group", "having",
+	"ilike", "in", "index", "inner", "insert", "intersect", "into", "is", "join",
+	"key", "last", "left", "like", "limit", "merge", "natural", "not", "null",
+	"nulls", "offset", "on", "or", "order", "outer", "over", "partition", "plan",
+	"prepare", "primary", "procedure", "references", "release", "rename",
+	"replace", "returns", "revoke", "right", "role", "rollback", "sample",
+	"savepoint", "schema", "select", "sequence", "set", "start", "table",
+	"temporary", "then", "trace", "transaction", "trigger", "true", "truncate",
+	"union", "unique", "update", "user", "using", "values", "view", "when",
+	"where", "window", "with",
+}
+
+// After these, a table name is expected
+var tableKeywords = map[string]bool{
+	"from": true, "join": true, "into": true, "update": true, "table": true,
+	"truncate": true, "describe": true,
+}
+
+// After these, a column name (or a function) is expected
+var columnKeywords = map[string]bool{
+	"select": true, "where": true, "and": true, "or": true, "on": true, "by": true,
+	"having": true, "set": true, "distinct": true, "not": true,
+}
+
+// These end the list of tables of a from clause
+var fromEndKeywords = map[string]bool{
+	"where": true, "on": true, "group": true, "order": true, "having": true,
+	"limit": true, "offset": true, "union": true, "except": true,
+	"intersect": true, "join": true, "inner": true, "left": true, "right": true,
+	"full": true, "cross": true, "natural": true, "using": true, "sample": true,
+}
+
+// Completes backslash commands (and some of their arguments), SQL keywords and
+// names from the catalog. The catalog is only used when it's loaded, until
+// then only keywords are completed.
+type completer struct {
+	prompt  libedit.EditLine
+	context *Context
+}
+
+func (c *completer) GetCompletions(word string) []string {
+	line, cursor := c.prompt.GetLineInfo()
+	runes := []rune(line)
+	if cursor > len(runes) {
+		cursor = len(runes)
+	}
+	before := string(runes[:cursor])
+
+	// , doesn't break words, "a,b" is completed as "b"
+	prefix := ""
+	if comma := strings.LastIndexByte(word, ','); comma != -1 {
+		prefix, word = word[:comma+1], word[comma+1:]
+	}
+
+	var candidates []string
+	if strings.HasPrefix(strings.TrimLeft(before, " "), "\\") && c.context.pending == "" {
+		candidates = c.commandCompletions(strings.TrimLeft(before, " "), word)
+	} else {
+		candidates = c.sqlCompletions(c.context.pending+before, c.context.pending+line, word)
+	}
+
+	matches := make([]string, 0, len(candidates))
+	seen := make(map[string]bool, len(candidates))
+	lower := strings.ToLower(word)
+	for _, candidate := range candidates {
+		if strings.HasPrefix(strings.ToLower(candidate), lower) && !seen[candidate] {
+			seen[candidate] = true
+			matches = append(matches, prefix+candidate)
+		}
+	}
+	sort.Strings(matches)
+	if len(matches) > 1 {
+		// like readline, libedit replaces the word with the first "match", which
+		// should be what all the matches have in common
+		matches = append([]string{commonPrefix(matches)}, matches...)
+	}
+	return matches
+}
+
+func commonPrefix(values []string) string {
+	prefix := values[0]
+	for _, value := range values[1:] {
+		for !strings.HasPrefix(value, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	return prefix
+}
+
+// \ breaks words, so the word being completed doesn't include it
+func (c *completer) commandCompletions(before string, word string) []string {
+	fields := strings.Fields(before)
+	if len(fields) <= 1 && !strings.HasSuffix(before, " ") {
+		names := make([]string, 0, len(cmds))
+		for name := range cmds {
+			names = append(names, name[1:])
+		}
+		return names
+	}
+
+	// only the first argument is completed
+	if len(fields) > 2 || (len(fields) == 2 && strings.HasSuffix(before, " ")) {
+		return nil
+	}
+	switch fields[0] {
+	case "\\f":
+		return outputs.Names()
+	case "\\pset":
+		return append([]string{"wrap", "truncate", "expanded"}, outputs.DisplaySettings...)
+	case "\\d", "\\d+":
+		return c.tableCompletions(word)
+	}
+	return nil
+}
+
+// before is the statement up to the cursor, statement is all of it (the from
+// clause can come after the cursor)
+func (c *completer) sqlCompletions(before string, statement string, word string) []string {
+	tokens := sqlTokens(strings.ToLower(before))
+	// the word being completed is the last token (unless it's empty)
+	if word != "" && len(tokens) > 0 {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	previous := ""
+	for i := len(tokens) - 1; i >= 0; i-- {
+		token := tokens[i]
+		if token == "," || token == "(" || token == ")" {
+			continue
+		}
+		previous = token
+		break
+	}
+
+	keywords := sqlKeywords
+	if word != "" && strings.ToUpper(word) == word && strings.ToLower(word) != word {
+		keywords = make([]string, len(sqlKeywords))
+		for i, keyword := range sqlKeywords {
+			keywords[i] = strings.ToUpper(keyword)
+		}
+	}
+
+	catalog := c.context.catalog
+	if catalog == nil {
+		return keywords
+	}
+
+	if tableKeywords[previous] {
+		return c.tableCompletions(word)
+	}
+
+	tables := fromTables(statement)
+
+	// table.column or alias.column
+	if dot := strings.LastIndexByte(word, '.'); dot != -1 {
+		qualifier := word[:dot]
+		if table, ok := tables[identifier(qualifier)]; ok {
+			qualifier = table
+		} else {
+			qualifier = identifier(qualifier)
+		}
+		var candidates []string
+		for _, column := range catalog.Columns(qualifier, c.schemas()...) {
+			candidates = append(candidates, word[:dot+1]+column)
+		}
+		return candidates
+	}
+
+	candidates := keywords
+	if columnKeywords[previous] || lastTokenIsComma(tokens) {
+		candidates = append([]string(nil), keywords...)
+		for _, table := range tables {
+			candidates = append(candidates, catalog.Columns(table, c.schemas()...)...)
+		}
+		candidates = append(candidates, catalog.Functions()...)
+	}
+	return candidates
+}
+
+// Schemas, and tables of the current schema, or the tables of schema when the
+// word is schema.
+func (c *completer) tableCompletions(word string) []string {
+	catalog := c.context.catalog
+	if catalog == nil {
+		return nil
+	}
+	if dot := strings.IndexByte(word, '.'); dot != -1 {
+		schema := word[:dot]
+		var candidates []string
+		for _, table := range catalog.Tables(schema) {
+			candidates = append(candidates, schema+"."+table)
+		}
+		return candidates
+	}
+
+	var candidates []string
+	for _, schema := range catalog.Schemas() {
+		candidates = append(candidates, schema+".")
+	}
+	return append(candidates, catalog.Tables(c.context.schema)...)
+}
+
+// Unqualified tables are looked for in the current schema, then in sys and tmp
+func (c *completer) schemas() []string {
+	return []string{c.context.schema, "sys", "tmp"}
+}
+
+func lastTokenIsComma(tokens []string) bool {
+	return len(tokens) > 0 && tokens[len(tokens)-1] == ","
+}
+
+// The tables of the statement's from clauses (and joins), by the name they're
+// referenced by (their alias, or their name)
+func fromTables(statement string) map[string]string {
+	tables := make(map[string]string)
+	tokens := sqlTokens(statement)
+
+	inFrom := false
+	// after from, join or a , in a from clause
+	expectTable := false
+	for i := 0; i < len(tokens); i++ {
+		token := strings.ToLower(tokens[i])
+		switch {
+		case token == "from" || token == "join":
+			inFrom, expectTable = true, true
+		case !inFrom:
+		case token == ",":
+			expectTable = true
+		case fromEndKeywords[token] || token == ";" || token == "(" || token == ")":
+			inFrom, expectTable = false, false
+		case expectTable && isName(token):
+			expectTable = false
+			table := identifier(tokens[i])
+			name := table
+			if dot := strings.LastIndexByte(table, '.'); dot != -1 {
+				name = table[dot+1:]
+			}
+			// [as] alias
+			if i+1 < len(tokens) && strings.ToLower(tokens[i+1]) == "as" {
+				i += 1
+			}
+			if next := i + 1; next < len(tokens) && !fromEndKeywords[strings.ToLower(tokens[next])] && isName(tokens[next]) {
+				name = identifier(tokens[next])
+				i += 1
+			}
+			tables[name] = table
+		}
+	}
+	return tables
+}
+
+// Unquoted identifiers are case insensitive (the server lower cases them)
+func identifier(token string) string {
+	parts := strings.Split(token, ".")
+	for i, part := range parts {
+		if strings.HasPrefix(part, `"`) {
+			parts[i] = strings.Trim(part, `"`)
+		} else {
+			parts[i] = strings.ToLower(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// The statement's tokens, without spaces and comments, and with qualified
+// names (e.g. sys.tables) as a single token
+func sqlTokens(statement string) []string {
+	var tokens []string
+	var previous lexer.Token
+	for _, token := range lexer.Tokenize(statement) {
+		qualified := token.Text == "." && isNameToken(previous) || isNameToken(token) && previous.Text == "."
+		if qualified && len(tokens) > 0 {
+			tokens[len(tokens)-1] += token.Text
+		} else if !token.Insignificant() {
+			tokens = append(tokens, token.Text)
+		}
+		previous = token
+	}
+	return tokens
+}
+
+func isNameToken(token lexer.Token) bool {
+	return token.Kind == lexer.WORD || token.Kind == lexer.IDENTIFIER
+}
+
+// Whether the token (from sqlTokens) is a, possibly qualified, name
+func isName(token string) bool {
+	if token == "" {
+		return false
+	}
+	return token[0] == '"' || token[0] == '_' || unicode.IsLetter([]rune(token)[0])
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_FromTables(t *testing.T) {
+	cases := []struct {
+		name      string
+		statement string
+		want      map[string]string
+	}{
+		{"no from", "select 1", map[string]string{}},
+		{"table", "select * from t where x = 1", map[string]string{"t": "t"}},
+		{"case", "select * from Items", map[string]string{"items": "items"}},
+		{"alias", "select * from t x where x.a = 1", map[string]string{"x": "t"}},
+		{"as alias", "select * from t AS X", map[string]string{"x": "t"}},
+		{"qualified", "select * from sys.tables", map[string]string{"tables": "sys.tables"}},
+		{"qualified alias", "select * from sys.tables st order by 1", map[string]string{"st": "sys.tables"}},
+		{"quoted", `select * from "My Table" m`, map[string]string{"m": "My Table"}},
+		{"quoted qualified", `select * from s."T"`, map[string]string{"T": "s.T"}},
+		{"quoted alias", `select * from t as "X"`, map[string]string{"X": "t"}},
+		{"list", "select * from a, b as y, sys.c where a.id = y.id", map[string]string{"a": "a", "y": "b", "c": "sys.c"}},
+		{"join", "select * from a join b on a.id = b.id", map[string]string{"a": "a", "b": "b"}},
+		{"joins", "select * from a x inner join b y on x.id = y.id left outer join c using (id) cross join d", map[string]string{"x": "a", "y": "b", "c": "c", "d": "d"}},
+		{"subquery", "select * from a where id in (select id from b z)", map[string]string{"a": "a", "z": "b"}},
+		{"from after the cursor", "select x. from t x", map[string]string{"x": "t"}},
+		{"comments", "select * from /* a */ t -- b\n x", map[string]string{"x": "t"}},
+	}
+
+	for _, c := range cases {
+		if got := fromTables(c.statement); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s:\n  got:  %v\n  want: %v", c.name, got, c.want)
+		}
+	}
+}
+
+func Test_SQLTokens(t *testing.T) {
+	cases := []struct {
+		name      string
+		statement string
+		want      []string
+	}{
+		{"empty", "", nil},
+		{"words", "select a,b from t;", []string{"select", "a", ",", "b", "from", "t", ";"}},
+		{"spaces and comments", "select /* x */ a -- y\n from t", []string{"select", "a", "from", "t"}},
+		{"qualified", "select s.t.c from s.t", []string{"select", "s.t.c", "from", "s.t"}},
+		{"quoted", `select "a b"."C" from "s".t`, []string{"select", `"a b"."C"`, "from", `"s".t`}},
+		{"qualified being typed", "select t.", []string{"select", "t."}},
+		{"strings", "select 'a.b', 1.5", []string{"select", "'a.b'", ",", "1.5"}},
+	}
+
+	for _, c := range cases {
+		if got := sqlTokens(c.statement); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s:\n  got:  %#v\n  want: %#v", c.name, got, c.want)
+		}
+	}
+}
+
+func Test_Identifier(t *testing.T) {
+	cases := []struct {
+		token string
+		want  string
+	}{
+		{"t", "t"},
+		{"Items", "items"},
+		{"SYS.Tables", "sys.tables"},
+		{`"Items"`, "Items"},
+		{`"My Schema".T`, "My Schema.t"},
+		{`s."T"`, "s.T"},
+	}
+
+	for _, c := range cases {
+		if got := identifier(c.token); got != c.want {
+			t.Errorf("%s: got %q, want %q", c.token, got, c.want)
+		}
+	}
+}
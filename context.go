@@ -3,50 +3,91 @@ package main
 import (
 	"fmt"
 	"io"
-	"net/url"
+	"net"
+	"strconv"
 	"strings"
 
 	"github.com/karlseguin/msql/driver"
+	"github.com/karlseguin/msql/outputs"
 	log "github.com/sirupsen/logrus"
 )
 
-const (
-	FORMAT_RAW      = "raw"
-	FORMAT_SQL      = "sql"
-	FORMAT_EXPANDED = "expanded"
-	FORMAT_TRASH    = "trash"
-)
-
 type Context struct {
-	out         io.Writer
+	// where output is currently written, see output()
+	out io.Writer
+
+	// the screen, and where \o, \tee and \g send results instead
+	stdout   io.Writer
+	redirect io.WriteCloser
+	tee      io.WriteCloser
+	once     io.WriteCloser
+
+	// for \g on its own line
+	lastQuery string
+
+	// for tab completion, nil until the session is interactive
+	catalog *catalog
+	// the lines of the statement being typed, before the current one
+	pending string
+	// the terminal's line editor (libedit), which questions (see confirm) are
+	// asked through, nil until the session is interactive
+	editor lineEditor
+
 	err         io.Writer
 	conn        driver.Conn
+	config      driver.Config
+	sessionId   int
 	preferences Preferences
-	format      string
+	format      outputs.Format
+	formatter   outputs.Formatter
+	display     outputs.Display
 	timing      bool
 	prompt      []byte
 	exitOnError bool
-	user        string
-	role        string
-	schema      string
-	host        string
-	port        string
-	database    string
-	version     string
-	release     string
-	id          string
-}
-
-func NewContext(conn driver.Conn, out io.Writer) *Context {
+	interactive bool
+
+	// when the connection drops, reconnect and restore these
+	autoReconnect bool
+	autoCommit    bool
+	timeZone      string
+
+	user     string
+	role     string
+	schema   string
+	host     string
+	port     string
+	database string
+	version  string
+	release  string
+	id       string
+	prepared map[string]*driver.Stmt
+}
+
+// The part of libedit.EditLine used to ask questions
+type lineEditor interface {
+	SetLeftPrompt(prompt string)
+	GetLine() (string, error)
+}
+
+func NewContext(config driver.Config, conn driver.Conn, out io.Writer) *Context {
 	userRoleSchema, err := conn.QueryRow("select current_user, current_role, current_schema")
 	if err != nil {
 		log.WithFields(log.Fields{"context": "build context (1)"}).Error(err)
 		userRoleSchema = []string{"unknown", "unknown", "unknown"}
 	}
 
+	// used to find our running query in sys.queue() when cancelling it, older
+	// servers don't have this function
+	sessionId := -1
+	if row, err := conn.QueryRow("select current_sessionid()"); err != nil {
+		log.WithFields(log.Fields{"context": "build context (session id)"}).Info(err)
+	} else if len(row) == 1 {
+		sessionId, _ = strconv.Atoi(row[0])
+	}
+
 	version := "unknown"
 	release := "unknown"
-	urlString := "//unknown/unknown"
+	urlString := ""
 	envs, err := conn.QueryRows("select name, value from sys.env() where name in ('merovingian_uri', 'monet_release', 'monet_version')")
 	if err != nil {
 		log.WithFields(log.Fields{"context": "build context (2)"}).Error(err)
@@ -66,40 +107,46 @@ func NewContext(conn driver.Conn, out io.Writer) *Context {
 		}
 	}
 
-	if strings.HasPrefix(urlString, "mapi:") {
-		urlString = urlString[5:]
-	}
-	parsed, err := url.Parse(urlString)
-	if err != nil {
-		log.WithFields(log.Fields{"context": "parse context url "}).Error(err)
-		parsed, _ = url.Parse("//unknown/unknown")
-	}
-
-	parts := strings.Split(parsed.Host, ":")
-	host := parts[0]
+	host := "unknown"
 	port := "???"
-	if len(parts) == 2 {
-		port = parts[1]
+	database := "unknown"
+	if urlString != "" {
+		// mapi:monetdb://host:port/database
+		if parsed, err := driver.ParseURL(urlString); err != nil {
+			log.WithFields(log.Fields{"context": "parse context url "}).Error(err)
+		} else {
+			host, port, _ = net.SplitHostPort(parsed.Host)
+			database = parsed.Database
+		}
 	}
-	database := strings.TrimLeft(parsed.Path, "/")
-
-	return &Context{
-		out:      out,
-		conn:     conn,
-		format:   FORMAT_SQL,
-		user:     userRoleSchema[0],
-		role:     userRoleSchema[1],
-		schema:   userRoleSchema[2],
-		host:     host,
-		port:     port,
-		database: database,
-		version:  version,
-		release:  release,
-		id:       fmt.Sprintf("%s:%s/%s", host, port, database),
+
+	context := &Context{
+		out:       out,
+		stdout:    out,
+		conn:      conn,
+		config:    config,
+		sessionId: sessionId,
+		user:      userRoleSchema[0],
+		role:      userRoleSchema[1],
+		schema:    userRoleSchema[2],
+		host:      host,
+		port:      port,
+		database:  database,
+		version:   version,
+		release:   release,
+		id:        fmt.Sprintf("%s:%s/%s", host, port, database),
+		prepared:  make(map[string]*driver.Stmt),
+
+		autoReconnect: true,
+		autoCommit:    true,
+		display:       outputs.DefaultDisplay(),
 	}
+	context.Format("sql")
+	return context
 }
 
 func (c *Context) Close() {
+	c.closeOutputs()
 	c.conn.Close()
 }
 
@@ -121,14 +168,42 @@ func (c *Context) WriteString(data string) {
 	io.WriteString(c.out, data)
 }
 
-func (c *Context) Format(format string) {
+func (c *Context) Format(name string) error {
+	format, ok := outputs.Lookup(name)
+	if !ok {
+		return fmt.Errorf("unknown format '%s'", name)
+	}
+	c.format = format
+	c.formatter = format.New()
+	return nil
+}
+
+func (c *Context) OutputFormat() outputs.Format {
+	return c.format
+}
+
+func (c *Context) Display() *outputs.Display {
+	return &c.display
+}
+
+// Switches to a formatter of the format, whose options have already been set
+func (c *Context) SetFormatter(format outputs.Format, formatter outputs.Formatter) {
 	c.format = format
+	c.formatter = formatter
 }
 
 func (c *Context) Timing(on bool) {
 	c.timing = on
 }
 
+func (c *Context) AutoCommit(on bool) error {
+	if err := c.conn.SetAutoCommit(on); err != nil {
+		return err
+	}
+	c.autoCommit = on
+	return nil
+}
+
 func (c *Context) Schema() string {
 	return c.schema
 }
@@ -137,8 +212,18 @@ func (c *Context) Conn() driver.Conn {
 	return c.conn
 }
 
+func (c *Context) Prepared() map[string]*driver.Stmt {
+	return c.prepared
+}
+
 func (c *Context) Query(sql string) {
-	query(c, sql)
+	query(c, sql, "")
+}
+
+// Runs a prepared statement, sql is what's shown for it (e.g. as a caption).
+// It can't be re-run after a reconnect, since it doesn't survive one.
+func (c *Context) Exec(sql string, stmt *driver.Stmt, args []interface{}) {
+	run(c, sql, func() error { return stmt.Send(args...) }, nil)
 }
 
 func (c *Context) template(t string) string {
@@ -157,11 +242,14 @@ func extractScalar(conn driver.Conn, query string, dflt string) string {
 		log.WithFields(log.Fields{"context": "building context send"}).Error(err)
 		return dflt
 	}
-	result, err := conn.ReadResult()
+	result, err := conn.NextResult()
 	if err != nil {
 		log.WithFields(log.Fields{"context": "building context result"}).Error(err)
 		return dflt
 	}
+	if result == nil {
+		return dflt
+	}
 	data, err := result.Next()
 	if err != nil {
 		log.WithFields(log.Fields{"context": "building context read"}).Error(err)
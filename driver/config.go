@@ -8,4 +8,37 @@ type Config struct {
 	Database string
 	Schema   string
 	Role     string
+
+	// path of a unix domain socket (e.g. /tmp/.s.monetdb.50000). When set, Host
+	// and TLS are ignored.
+	Socket string
+
+	// nil for a plain TCP connection
+	TLS *TLSConfig
+
+	// Reads and writes the files of COPY ... ON CLIENT statements, which
+	// aren't supported when nil (see DirectoryTransfer)
+	FileTransfer FileTransfer
+}
+
+type TLSConfig struct {
+	// PEM file of the CA(s) to verify the server against. When empty, the
+	// system's CAs are used.
+	CAFile string
+
+	// PEM files of the client certificate and key, for servers (or TLS
+	// terminators) requiring client authentication
+	CertFile string
+	KeyFile  string
+
+	// Overrides the name the server certificate is verified against, which
+	// defaults to the host we're connecting to.
+	ServerName string
+
+	// Pins the server certificate: sha256:HEX where HEX is the (start of the)
+	// sha256 of the server's DER encoded certificate. Colons in HEX are ignored.
+	// When set, the certificate chain isn't otherwise verified.
+	CertHash string
+
+	InsecureSkipVerify bool
 }
@@ -6,9 +6,11 @@ import (
 	"crypto/sha512"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"hash"
 	"io"
-	"log"
+	"math"
+	"math/big"
 	"net"
 	"net/url"
 	"strconv"
@@ -20,38 +22,83 @@ type Conn struct {
 	net.Conn
 	buffer  []byte
 	scratch []byte
+	reply   *reply
+	session *session
+
+	// nil when COPY ... ON CLIENT isn't supported
+	transfer FileTransfer
+}
+
+// Session state we learn about from the server's responses
+type session struct {
+	// false while in a transaction, or when auto-commit was turned off
+	autoCommit bool
+}
+
+// Tracks the message the server is currently sending us. A single message can
+// contain multiple responses (e.g. "select 1; select 2;"), so we need to keep
+// whatever hasn't been consumed yet around for the next call to NextResult.
+type reply struct {
+	// data read from the socket but not yet consumed
+	buffer bytes.Buffer
+
+	// whether the last frame of the message has been read
+	fin bool
+
+	// whether NextResult has already been called for this message
+	started bool
+
+	// a file was just uploaded, the server might acknowledge the empty message
+	// which ended it
+	uploaded bool
 }
 
 func Open(config Config) (Conn, error) {
-	socket, err := net.DialTimeout("tcp", config.Host, time.Second*5)
+	socket, err := dial(config)
 	if err != nil {
 		return Conn{}, err
 	}
 
 	c := Conn{
-		Conn:    socket,
-		scratch: make([]byte, 2),
-		buffer:  make([]byte, 8192), // 8190 max frame size + 2 for header
+		Conn:     socket,
+		scratch:  make([]byte, 2),
+		buffer:   make([]byte, 8192), // 8190 max frame size + 2 for header
+		reply:    new(reply),
+		session:  &session{autoCommit: true},
+		transfer: config.FileTransfer,
 	}
 
-	redirect, err := c.authenticate(config, 0)
+	redirect, level, err := c.authenticate(config, 0)
 	if err != nil {
 		socket.Close()
 		return Conn{}, err
 	}
 	if redirect != nil {
 		socket.Close()
+		if redirect.Host == "" && redirect.Path != "" {
+			// mapi:monetdb:///path/to/socket?database=x
+			config.Socket = redirect.Path
+			return Open(config)
+		}
+		config.Socket = ""
 		config.Host = redirect.Host
+		// our TLS settings carry over, but a monetdbs:// redirect requires TLS
+		if redirect.Scheme == "monetdbs" && config.TLS == nil {
+			config.TLS = new(TLSConfig)
+		}
 		return Open(config)
 	}
 
 	c.SetDeadline(time.Now().Add(time.Second * 5))
-	if err := c.configure(config); err != nil {
-		socket.Close()
-		return Conn{}, err
+	if level <= OPTION_REPLY_SIZE {
+		// older server, which didn't let us set this as part of the login
+		if err := c.disableReplySize(); err != nil {
+			socket.Close()
+			return Conn{}, err
+		}
 	}
 
-	if err := c.disableReplySize(); err != nil {
+	if err := c.configure(config, level); err != nil {
 		socket.Close()
 		return Conn{}, err
 	}
@@ -60,34 +107,90 @@ func Open(config Config) (Conn, error) {
 	return c, nil
 }
 
-func (c Conn) authenticate(config Config, tries uint8) (*url.URL, error) {
+// Handshake options can be sent as part of the login, saving round trips. The
+// server advertises the level it supports (sql=N in the challenge) and
+// supports every option with a lower level.
+const (
+	OPTION_AUTO_COMMIT       = 1
+	OPTION_REPLY_SIZE        = 2
+	OPTION_SIZE_HEADER       = 3
+	OPTION_COLUMNAR_PROTOCOL = 4
+	OPTION_TIME_ZONE         = 5
+)
+
+func handshakeOptions(level int) string {
+	_, offset := time.Now().Zone()
+	options := []struct {
+		level int
+		value string
+	}{
+		{OPTION_AUTO_COMMIT, "auto_commit=1"},
+		{OPTION_REPLY_SIZE, "reply_size=-1"},
+		{OPTION_SIZE_HEADER, "size_header=0"},
+		{OPTION_TIME_ZONE, "time_zone=" + strconv.Itoa(offset)},
+	}
+
+	var values []string
+	for _, option := range options {
+		if option.level < level {
+			values = append(values, option.value)
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+// parses the sql=N handshake options level from the challenge
+func handshakeLevel(parts []string) (int, error) {
+	// without options, the challenge ends with a ':', leaving an empty 7th part
+	if len(parts) < 8 {
+		return 0, nil
+	}
+	for _, option := range strings.Split(parts[6], ",") {
+		if strings.HasPrefix(option, "sql=") {
+			level, err := strconv.Atoi(option[4:])
+			if err != nil {
+				return 0, detailedDriverError("invalid handshake options level", option)
+			}
+			return level, nil
+		}
+	}
+	return 0, nil
+}
+
+// Returns the redirect, if any, or the handshake options level of the server
+func (c Conn) authenticate(config Config, tries uint8) (*url.URL, int, error) {
 	if tries == 10 {
-		return nil, driverError("too many proxy login iterations")
+		return nil, 0, driverError("too many proxy login iterations")
 	}
 
 	c.SetDeadline(time.Now().Add(time.Second * 10))
 	challenge, err := c.readMessageString()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	parts := strings.Split(string(challenge), ":")
-	if len(parts) != 7 {
-		return nil, detailedDriverError("invalid challenge response", string(challenge))
+	if len(parts) < 7 {
+		return nil, 0, detailedDriverError("invalid challenge response", string(challenge))
 	}
 	if parts[2] != "9" {
-		return nil, detailedDriverError("invalid challenge version", parts[2])
+		return nil, 0, detailedDriverError("invalid challenge version", parts[2])
+	}
+
+	level, err := handshakeLevel(parts)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	salt := parts[0]
 
 	authHasher, authName := parseAuthType(parts[3])
 	if authHasher == nil {
-		return nil, detailedDriverError("no supported auth types", parts[3])
+		return nil, 0, detailedDriverError("no supported auth types", parts[3])
 	}
 
 	algoHasher := parseHashAlgo(parts[5])
 	if algoHasher == nil {
-		return nil, detailedDriverError("unsupported hash algorithm", parts[5])
+		return nil, 0, detailedDriverError("unsupported hash algorithm", parts[5])
 	}
 
 	algoHasher.Write([]byte(config.Password))
@@ -97,19 +200,32 @@ func (c Conn) authenticate(config Config, tries uint8) (*url.URL, error) {
 	authHasher.Write([]byte(salt))
 	digest := hex.EncodeToString(authHasher.Sum(nil))
 
-	err = c.Send("LIT:", config.UserName, ":", authName, digest, ":sql:", config.Database, ":")
+	login := []string{"LIT:", config.UserName, ":", authName, digest, ":sql:", config.Database, ":"}
+	fileTrans := ""
+	if config.FileTransfer != nil {
+		// we handle COPY ... ON CLIENT
+		fileTrans = "FILETRANS"
+	}
+	if level > 0 {
+		// the FILETRANS field, followed by our options
+		login = append(login, fileTrans, ":", handshakeOptions(level), ":")
+	} else if fileTrans != "" {
+		login = append(login, fileTrans, ":")
+	}
+
+	err = c.Send(login...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	reply, err := c.readMessageString()
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	if reply == "" {
 		// success
-		return nil, nil
+		return nil, level, nil
 	}
 
 	if strings.HasPrefix(reply, "^mapi:merovingian:") {
@@ -122,54 +238,81 @@ func (c Conn) authenticate(config Config, tries uint8) (*url.URL, error) {
 		// 1 - len(u) -1 to strip out the leading ^mapi:  and the trailing \n
 		url, err := url.Parse(u[6 : len(u)-1])
 		if err != nil {
-			return nil, detailedDriverError("invalid login redirect", reply)
+			return nil, 0, detailedDriverError("invalid login redirect", reply)
 		}
-		return url, nil
+		return url, 0, nil
 	}
 
-	return nil, detailedDriverError("invalid login response", reply)
+	return nil, 0, detailedDriverError("invalid login response", reply)
 }
 
-func (c Conn) configure(config Config) error {
-	if config.Schema != "" {
-		if err := c.set("schema", config.Schema); err != nil {
-			return err
+// Everything the handshake options didn't cover is set in a single batch
+func (c Conn) configure(config Config, level int) error {
+	var sql []string
+	if level <= OPTION_TIME_ZONE {
+		_, offset := time.Now().Zone()
+		sign := '+'
+		if offset < 0 {
+			sign = '-'
+			offset = -offset
 		}
+		sql = append(sql, fmt.Sprintf("set time zone interval '%c%02d:%02d' hour to minute", sign, offset/3600, offset%3600/60))
+	}
+	if config.Schema != "" {
+		sql = append(sql, "set schema "+QuoteIdentifier(config.Schema))
 	}
-
 	if config.Role != "" {
-		if err := c.set("role", config.Role); err != nil {
+		sql = append(sql, "set role "+QuoteIdentifier(config.Role))
+	}
+	if len(sql) == 0 {
+		return nil
+	}
+
+	if err := c.Send("s", strings.Join(sql, "; "), ";"); err != nil {
+		return err
+	}
+	for {
+		result, err := c.NextResult()
+		if err != nil || result == nil {
 			return err
 		}
 	}
-	return nil
 }
 
-func (c Conn) set(field string, value string) error {
-	if err := c.Send("sset  ", field, " ", value, ";"); err != nil {
+func (c Conn) disableReplySize() error {
+	if err := c.Send("Xreply_size -1\n"); err != nil {
 		return err
 	}
-	data, err := c.readMessage()
-	if err != nil {
+	if _, err := c.readMessage(); err != nil {
 		return err
 	}
-	if !bytes.HasPrefix(data, []byte("&3 ")) {
-		return detailedDriverError("invalid response to SET command", string(data))
-	}
 	return nil
 }
 
-func (c Conn) disableReplySize() error {
-	if err := c.Send("Xreply_size -1\n"); err != nil {
+// Whether the server is in auto-commit mode. This is false while a transaction
+// is open.
+func (c Conn) AutoCommit() bool {
+	return c.session.autoCommit
+}
+
+func (c Conn) SetAutoCommit(on bool) error {
+	value := "0"
+	if on {
+		value = "1"
+	}
+	if err := c.Send("Xauto_commit ", value); err != nil {
 		return err
 	}
 	if _, err := c.readMessage(); err != nil {
 		return err
 	}
+	c.session.autoCommit = on
 	return nil
 }
 
-func (c Conn) ReadResult() (Result, error) {
+// Returns the next response of the current reply, or nil once every response
+// has been read. Any unread rows of the previous result are skipped.
+func (c Conn) NextResult() (Result, error) {
 	return newResult(c)
 }
 
@@ -189,6 +332,9 @@ func (c Conn) QueryRows(sql string) ([][]string, error) {
 	if err != nil {
 		return nil, err
 	}
+	if r == nil {
+		return nil, nil
+	}
 	return r.Rows()
 }
 
@@ -201,53 +347,24 @@ func (c Conn) PrepareRow(sql string, values ...interface{}) ([]string, error) {
 }
 
 func (c Conn) PrepareRows(sql string, values ...interface{}) ([][]string, error) {
-	r, err := c.prepareAndExec(sql, values...)
+	stmt, err := c.Prepare(sql)
 	if err != nil {
 		return nil, err
 	}
-	return r.Rows()
-}
 
-// Our prepare / exec is lame. It only deals with basic values, but for what
-// we need from msql, it's good enough.
-func (c Conn) prepareAndExec(sql string, values ...interface{}) (Result, error) {
-	if err := c.Send("sprepare", sql, ";"); err != nil {
-		return nil, err
-	}
-	data, err := c.readMessage()
+	result, err := stmt.Exec(values...)
 	if err != nil {
+		stmt.Close()
 		return nil, err
 	}
-	parts := bytes.SplitN(data, []byte(" "), 3)
-	if len(parts) != 3 {
-		return nil, detailedDriverError("invalid prepare response", string(data))
-	}
-
-	id := string(parts[1])
-	encoded := make([]string, len(values))
-	for i, value := range values {
-		encoded[i] = encode(value)
-	}
 
-	if err := c.Send("sexec ", id, "(", strings.Join(encoded, ", "), ");"); err != nil {
-		return nil, err
-	}
-
-	result, err := newResult(c)
+	// the result has to be read before we close the statement
+	rows, err := result.Rows()
 	if err != nil {
+		stmt.Close()
 		return nil, err
 	}
-
-	if err := c.Send("sdeallocate ", id, ";"); err != nil {
-		return nil, err
-	}
-
-	// drain the response from deallocate
-	if _, err := c.readMessage(); err != nil {
-		return nil, err
-	}
-
-	return result, nil
+	return rows, stmt.Close()
 }
 
 func (c Conn) readMessageString() (string, error) {
@@ -297,15 +414,143 @@ func (c Conn) ReadFrame() ([]byte, bool, error) {
 	if err != nil {
 		return nil, false, networkError(err)
 	}
+	c.reply.fin = fin == 1
 	return data, fin == 1, nil
 }
 
+// Returns the next line of the current reply (without the trailing newline),
+// or nil once the reply has been fully consumed. The server's prompts are
+// answered here, so that every reader of the reply (including the raw output)
+// stays in sync with the server, and are never returned.
+// Like ReadFrame, the returned slice is only valid until the next read.
+func (c Conn) ReadLine() ([]byte, error) {
+	for {
+		line, err := c.readLine()
+		if err != nil || line == nil {
+			return line, err
+		}
+		answered, err := c.answerPrompt(line)
+		if err != nil {
+			return nil, err
+		}
+		if !answered {
+			return line, nil
+		}
+	}
+}
+
+// Whether the line was a prompt from the server, which has been answered
+func (c Conn) answerPrompt(line []byte) (bool, error) {
+	if string(line) == PROMPT_FILE {
+		// the server wants a file read (or written) for a COPY ... ON CLIENT
+		c.reply.uploaded = false
+		request, err := c.readLine()
+		if err != nil {
+			return false, err
+		}
+		return true, c.transferFile(string(request))
+	}
+
+	if string(line) != PROMPT_MORE {
+		c.reply.uploaded = false
+		return false, nil
+	}
+
+	if c.reply.uploaded {
+		// the server acknowledging the end of an upload
+		c.reply.uploaded = false
+		if c.reply.buffer.Len() == 0 && c.reply.fin {
+			c.reply.fin = false
+		}
+		return true, nil
+	}
+
+	// the server wants more data for a COPY ... FROM STDIN, everything we have
+	// was sent with the statement, an empty message says there's no more
+	c.reply.buffer.Reset()
+	c.reply.fin = false
+	return true, c.write(nil)
+}
+
+func (c Conn) readLine() ([]byte, error) {
+	r := c.reply
+	for {
+		data := r.buffer.Bytes()
+		if i := bytes.IndexByte(data, '\n'); i != -1 {
+			r.buffer.Next(i + 1)
+			return data[:i], nil
+		}
+		if r.fin {
+			if len(data) == 0 {
+				return nil, nil
+			}
+			r.buffer.Reset()
+			return data, nil
+		}
+		if err := c.fill(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Returns the first byte of the next line of the current reply without
+// consuming it, or 0 once the reply has been fully consumed.
+func (c Conn) peek() (byte, error) {
+	r := c.reply
+	for r.buffer.Len() == 0 {
+		if r.fin {
+			return 0, nil
+		}
+		if err := c.fill(); err != nil {
+			return 0, err
+		}
+	}
+	return r.buffer.Bytes()[0], nil
+}
+
+// Reads the next frame of the current reply into the reply buffer
+func (c Conn) fill() error {
+	data, _, err := c.ReadFrame()
+	if err != nil {
+		return err
+	}
+	c.reply.buffer.Write(data)
+	return nil
+}
+
+// Reads (and discards) whatever is left of the current reply. Without this,
+// anything the caller didn't read would be seen as the reply to the next
+// command.
+func (c Conn) discard() error {
+	c.reply.buffer.Reset()
+	for !c.reply.fin {
+		if _, _, err := c.ReadFrame(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (c Conn) readN(data []byte) ([]byte, error) {
 	_, err := io.ReadFull(c.Conn, data)
 	return data, err
 }
 
+// Sends a message, made of the concatenation of parts. The data of a
+// COPY ... FROM STDIN statement goes in the same message, after the statement
+// (e.g. Send("s", "copy 2 records into t from stdin;\n", "1\n2\n")).
 func (c Conn) Send(parts ...string) error {
+	if err := c.discard(); err != nil {
+		return err
+	}
+	c.reply.started = false
+	c.reply.fin = false
+	c.reply.uploaded = false
+	return c.write(parts)
+}
+
+// Writes the parts as a message, without touching the current reply
+func (c Conn) write(parts []string) error {
 	l := 0
 	for _, part := range parts {
 		l += len(part)
@@ -319,7 +564,7 @@ func (c Conn) Send(parts ...string) error {
 	scratch := c.scratch
 	binary.LittleEndian.PutUint16(scratch, uint16(l<<1|1))
 	if _, err := c.Write(scratch); err != nil {
-		return nil
+		return networkError(err)
 	}
 	for _, part := range parts {
 		if _, err := c.Write([]byte(part)); err != nil {
@@ -330,37 +575,34 @@ func (c Conn) Send(parts ...string) error {
 	return nil
 }
 
+// Frames are filled straight from the parts, which can be large (e.g. the data
+// of a COPY), without first joining them. Only the last frame has the fin bit.
 func (c Conn) multiFrameSend(l int, parts []string) error {
-	var buffer strings.Builder
-	buffer.Grow(l)
+	frame := make([]byte, 8192)
+	n := 0
 	for _, part := range parts {
-		buffer.WriteString(part)
-	}
-
-	data := []byte(buffer.String())
-	for {
-		if len(data) > 8190 {
-			// max-length + non-fin
-			if _, err := c.Write([]byte{252, 63}); err != nil {
-				return err
+		for len(part) > 0 {
+			copied := copy(frame[2+n:], part)
+			part = part[copied:]
+			n += copied
+			l -= copied
+			if n < 8190 || l == 0 {
+				continue
 			}
-			if _, err := c.Write(data[0:8190]); err != nil {
-				return err
-			}
-			data = data[8190:]
-		} else {
-			scratch := c.scratch
-			binary.LittleEndian.PutUint16(scratch, uint16(len(data)<<1|1))
-			if _, err := c.Write(scratch); err != nil {
-				return err
-			}
-			if _, err := c.Write(data); err != nil {
-				return err
+			// max-length + non-fin
+			frame[0], frame[1] = 252, 63
+			if _, err := c.Write(frame); err != nil {
+				return networkError(err)
 			}
-			// we're done
-			return nil
+			n = 0
 		}
 	}
+
+	binary.LittleEndian.PutUint16(frame, uint16(n<<1|1))
+	if _, err := c.Write(frame[:2+n]); err != nil {
+		return networkError(err)
+	}
+	return nil
 }
 
 func contains(haystack []string, needle string) bool {
@@ -404,13 +646,137 @@ func parseHashAlgo(algo string) hash.Hash {
 	}
 }
 
-func encode(value interface{}) string {
+// When we know the type of the parameter (from a prepared statement), we can
+// be more precise for some values (like a time.Time going into a date).
+func encodeAs(tpe string, value interface{}) (string, error) {
 	switch v := value.(type) {
+	case nil:
+		return "NULL", nil
 	case int:
-		return strconv.Itoa(v)
+		return strconv.Itoa(v), nil
+	case int8:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int16:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int32:
+		return strconv.FormatInt(int64(v), 10), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint8:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint16:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint32:
+		return strconv.FormatUint(uint64(v), 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float32:
+		return encodeFloat(float64(v), 32)
+	case float64:
+		return encodeFloat(v, 64)
+	case bool:
+		if v {
+			return "true", nil
+		}
+		return "false", nil
 	case string:
-		return "'" + strings.ReplaceAll(strings.ReplaceAll(v, "\\", "\\\\"), "'", "\\'") + "'"
+		return encodeString(tpe, v), nil
+	case []byte:
+		return "blob '" + hex.EncodeToString(v) + "'", nil
+	case *big.Int:
+		return v.String(), nil
+	case Decimal:
+		return v.String(), nil
+	case *Decimal:
+		return v.String(), nil
+	case time.Duration:
+		return fmt.Sprintf("interval '%s' second", strconv.FormatFloat(v.Seconds(), 'f', 3, 64)), nil
+	case time.Time:
+		switch tpe {
+		case "date":
+			return "date '" + v.Format("2006-01-02") + "'", nil
+		case "time":
+			return "time '" + v.Format("15:04:05.999999") + "'", nil
+		case "timetz":
+			return "timetz '" + v.Format("15:04:05.999999-07:00") + "'", nil
+		case "timestamp":
+			return "timestamp '" + v.Format("2006-01-02 15:04:05.999999") + "'", nil
+		case "timestamptz":
+			return "timestamptz '" + v.Format("2006-01-02 15:04:05.999999-07:00") + "'", nil
+		}
+		return "", driverError(fmt.Sprintf("cannot encode a time.Time as %s", tpe))
+	}
+	return "", driverError(fmt.Sprintf("cannot encode %T", value))
+}
+
+// SQL has no literal for NaN or infinity
+func encodeFloat(f float64, bitSize int) (string, error) {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return "", driverError(fmt.Sprintf("cannot encode %v", f))
 	}
-	log.Panicf("cannot encode %v", value)
-	return ""
+	return strconv.FormatFloat(f, 'g', -1, bitSize), nil
+}
+
+// A string is converted to the parameter's type when it's a valid value of it,
+// which lets callers pass values as text (e.g. typed by a user). Anything else
+// is passed as a string, for the server to convert (or reject).
+func encodeString(tpe string, value string) string {
+	switch tpe {
+	case "tinyint", "smallint", "int", "bigint", "hugeint":
+		if n, ok := new(big.Int).SetString(value, 10); ok {
+			return n.String()
+		}
+	case "decimal":
+		if d, err := ParseDecimal(value); err == nil {
+			return d.String()
+		}
+	case "real", "float", "double":
+		if f, err := strconv.ParseFloat(value, 64); err == nil && !math.IsInf(f, 0) && !math.IsNaN(f) {
+			return strconv.FormatFloat(f, 'g', -1, 64)
+		}
+	case "boolean":
+		if value == "true" || value == "false" {
+			return value
+		}
+	case "date", "time", "timetz", "timestamp", "timestamptz", "sec_interval", "day_interval", "month_interval":
+		return Literal(tpe, value)
+	}
+	return quote(value)
+}
+
+// Like encodeAs, but for the text of a value as returned by the server (e.g.
+// Next()), which is how outputs can turn a result back into sql. The value
+// must not be NULL.
+func Literal(tpe string, value string) string {
+	switch tpe {
+	case "tinyint", "smallint", "int", "bigint", "hugeint", "decimal", "real", "float", "double", "boolean":
+		return value
+	case "date", "time", "timetz", "timestamp", "timestamptz":
+		return tpe + " " + quote(value)
+	case "sec_interval":
+		return "interval " + quote(value) + " second"
+	case "day_interval":
+		return "interval " + quote(value) + " day"
+	case "month_interval":
+		return "interval " + quote(value) + " month"
+	case "blob":
+		// already hex encoded
+		return "blob " + quote(value)
+	}
+	// strings, and types like json, uuid and inet which the server casts from
+	// a string
+	return quote(value)
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(strings.ReplaceAll(s, "\\", "\\\\"), "'", "\\'") + "'"
+}
+
+// A name (e.g. of a schema or column) as SQL. Always quoted, since names which
+// look plain can still be keywords (e.g. a column named user or order), and
+// quoting keeps their case.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
 }
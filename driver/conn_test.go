@@ -0,0 +1,97 @@
+package driver
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type testFrame struct {
+	data string
+	fin  bool
+}
+
+func readTestFrames(r io.Reader) ([]testFrame, error) {
+	var frames []testFrame
+	header := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return nil, err
+		}
+		length := binary.LittleEndian.Uint16(header)
+		data := make([]byte, length>>1)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+		frames = append(frames, testFrame{string(data), length&1 == 1})
+		if length&1 == 1 {
+			return frames, nil
+		}
+	}
+}
+
+// A message is split into frames of at most 8190 bytes, only the last one has
+// the fin bit. The parts (e.g. a COPY statement and its data) don't have to
+// line up with the frames.
+func Test_Send_Frames(t *testing.T) {
+	received := make(chan []testFrame, 1)
+	host := testServer(t, func(c net.Conn) {
+		for {
+			frames, err := readTestFrames(c)
+			if err != nil {
+				return
+			}
+			received <- frames
+			writeTestMessage(c, "&3 0\n")
+		}
+	})
+	conn := testConnect(t, Config{Host: host})
+	defer conn.Close()
+
+	cases := []struct {
+		// the length of each part
+		parts []int
+		// the length of each frame
+		frames []int
+	}{
+		{[]int{0}, []int{0}},
+		{[]int{1, 8188}, []int{8189}},
+		{[]int{8190}, []int{8190}},
+		{[]int{1, 8188, 1, 1}, []int{8190, 1}},
+		{[]int{8191}, []int{8190, 1}},
+		{[]int{1, 16379}, []int{8190, 8190}},
+		{[]int{16379}, []int{8190, 8189}},
+		{[]int{8190, 8190}, []int{8190, 8190}},
+		{[]int{2, 35, 1, 16343}, []int{8190, 8190, 1}},
+		{[]int{16381}, []int{8190, 8190, 1}},
+	}
+
+	for _, c := range cases {
+		parts := make([]string, len(c.parts))
+		for i, length := range c.parts {
+			parts[i] = strings.Repeat(string(rune('a'+i)), length)
+		}
+		if err := conn.Send(parts...); err != nil {
+			t.Fatal(err)
+		}
+		frames := <-received
+		var data strings.Builder
+		lengths := make([]int, len(frames))
+		for i, frame := range frames {
+			lengths[i] = len(frame.data)
+			data.WriteString(frame.data)
+			if frame.fin != (i == len(frames)-1) {
+				t.Errorf("%v: frame %d has fin %v", c.parts, i, frame.fin)
+			}
+		}
+		if !reflect.DeepEqual(lengths, c.frames) {
+			t.Errorf("%v: got frames of %v, want %v", c.parts, lengths, c.frames)
+		}
+		if data.String() != strings.Join(parts, "") {
+			t.Errorf("%v: the frames don't add up to the parts", c.parts)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package driver
+
+import (
+	sqldriver "database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MonetDB's decimal can hold up to 38 digits, more than float64 can represent
+// exactly. The value is Unscaled * 10^-Scale
+type Decimal struct {
+	Unscaled *big.Int
+	Scale    int
+}
+
+func ParseDecimal(s string) (Decimal, error) {
+	scale := 0
+	if dot := strings.IndexByte(s, '.'); dot != -1 {
+		scale = len(s) - dot - 1
+		s = s[:dot] + s[dot+1:]
+	}
+	unscaled, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return Decimal{}, detailedDriverError("invalid decimal", s)
+	}
+	return Decimal{Unscaled: unscaled, Scale: scale}, nil
+}
+
+func (d Decimal) String() string {
+	if d.Unscaled == nil {
+		return "0"
+	}
+	s := d.Unscaled.String()
+	if d.Scale <= 0 {
+		return s
+	}
+
+	negative := s[0] == '-'
+	if negative {
+		s = s[1:]
+	}
+	if len(s) <= d.Scale {
+		s = strings.Repeat("0", d.Scale-len(s)+1) + s
+	}
+	s = s[:len(s)-d.Scale] + "." + s[len(s)-d.Scale:]
+	if negative {
+		return "-" + s
+	}
+	return s
+}
+
+func (d Decimal) Float64() float64 {
+	f, _ := strconv.ParseFloat(d.String(), 64)
+	return f
+}
+
+// Unscaled * 10^-Scale seconds, without going through a float64 which can't
+// represent every value exactly. Digits smaller than a nanosecond are dropped.
+// false if it doesn't fit in a time.Duration.
+func (d Decimal) duration() (time.Duration, bool) {
+	ns := new(big.Int).Mul(d.Unscaled, big.NewInt(int64(time.Second)))
+	if d.Scale > 0 {
+		ns.Quo(ns, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(d.Scale)), nil))
+	}
+	if !ns.IsInt64() {
+		return 0, false
+	}
+	return time.Duration(ns.Int64()), true
+}
+
+// Lets a Decimal be scanned into by database/sql
+func (d *Decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case string:
+		parsed, err := ParseDecimal(v)
+		if err != nil {
+			return err
+		}
+		*d = parsed
+		return nil
+	case []byte:
+		return d.Scan(string(v))
+	case int64:
+		*d = Decimal{Unscaled: big.NewInt(v)}
+		return nil
+	}
+	return fmt.Errorf("cannot scan %T into a Decimal", src)
+}
+
+// Lets a Decimal be used as an argument to database/sql
+func (d Decimal) Value() (sqldriver.Value, error) {
+	return d.String(), nil
+}
+
+// Decodes the text representation of a value into a Go value based on the
+// column's type:
+//
+//	tinyint, smallint, int, bigint     int64
+//	hugeint                            *big.Int
+//	decimal                            Decimal
+//	real, float, double                float64
+//	boolean                            bool
+//	date, time, timestamp (+tz)        time.Time
+//	sec_interval, day_interval         time.Duration
+//	month_interval                     int64 (months)
+//	blob                               []byte
+//	json                               []byte
+//	everything else                    string
+//
+// NULL is returned as nil. Strings have already been unquoted. quoted is
+// needed to tell the NULL apart from the string 'NULL'.
+func decode(tpe string, value string, quoted bool) (interface{}, error) {
+	if !quoted && value == "NULL" {
+		return nil, nil
+	}
+
+	switch tpe {
+	case "tinyint", "smallint", "int", "bigint":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, decodeError(tpe, value)
+		}
+		return n, nil
+	case "hugeint":
+		n, ok := new(big.Int).SetString(value, 10)
+		if !ok {
+			return nil, decodeError(tpe, value)
+		}
+		return n, nil
+	case "decimal":
+		return ParseDecimal(value)
+	case "real", "float", "double":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, decodeError(tpe, value)
+		}
+		return f, nil
+	case "boolean":
+		return value == "true", nil
+	case "date":
+		return decodeTime(tpe, "2006-01-02", value)
+	case "time":
+		return decodeTime(tpe, "15:04:05", value)
+	case "timetz":
+		return decodeTime(tpe, "15:04:05-07:00", value)
+	case "timestamp":
+		return decodeTime(tpe, "2006-01-02 15:04:05", value)
+	case "timestamptz":
+		return decodeTime(tpe, "2006-01-02 15:04:05-07:00", value)
+	case "sec_interval":
+		// seconds with millisecond precision, e.g. 3.500
+		d, err := ParseDecimal(value)
+		if err != nil {
+			return nil, decodeError(tpe, value)
+		}
+		duration, ok := d.duration()
+		if !ok {
+			return nil, decodeError(tpe, value)
+		}
+		return duration, nil
+	case "day_interval":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, decodeError(tpe, value)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	case "month_interval":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, decodeError(tpe, value)
+		}
+		return n, nil
+	case "blob":
+		b, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, decodeError(tpe, value)
+		}
+		return b, nil
+	case "json":
+		return []byte(value), nil
+	}
+	return value, nil
+}
+
+func decodeTime(tpe string, layout string, value string) (interface{}, error) {
+	// time.Parse accepts fractional seconds even if the layout doesn't have any
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return nil, decodeError(tpe, value)
+	}
+	return t, nil
+}
+
+func decodeError(tpe string, value string) error {
+	return detailedDriverError("invalid "+tpe+" value", value)
+}
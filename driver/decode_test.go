@@ -0,0 +1,101 @@
+package driver
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_Decode(t *testing.T) {
+	hugeint, _ := new(big.Int).SetString("-170141183460469231731687303715884105727", 10)
+	unscaled, _ := new(big.Int).SetString("12345678901234567890123456789012345678", 10)
+	date := func(year int, month time.Month, day, hour, min, sec, nsec int, offset int) time.Time {
+		location := time.UTC
+		if offset != 0 {
+			location = time.FixedZone("", offset)
+		}
+		return time.Date(year, month, day, hour, min, sec, nsec, location)
+	}
+
+	cases := []struct {
+		tpe    string
+		value  string
+		quoted bool
+		want   interface{}
+	}{
+		{"int", "NULL", false, nil},
+		{"varchar", "NULL", false, nil},
+		{"varchar", "NULL", true, "NULL"},
+		{"clob", "", true, ""},
+
+		{"tinyint", "-128", false, int64(-128)},
+		{"smallint", "32767", false, int64(32767)},
+		{"int", "-42", false, int64(-42)},
+		{"bigint", "9223372036854775807", false, int64(9223372036854775807)},
+		{"hugeint", "-170141183460469231731687303715884105727", false, hugeint},
+		{"decimal", "1234567890123456789012345678901234567.8", false, Decimal{Unscaled: unscaled, Scale: 1}},
+		{"decimal", "-0.05", false, Decimal{Unscaled: big.NewInt(-5), Scale: 2}},
+		{"real", "1.5", false, 1.5},
+		{"double", "-2.25e+10", false, -2.25e10},
+		{"boolean", "true", false, true},
+		{"boolean", "false", false, false},
+
+		{"date", "2021-03-04", false, date(2021, 3, 4, 0, 0, 0, 0, 0)},
+		{"time", "13:14:15.25", false, date(0, 1, 1, 13, 14, 15, 250000000, 0)},
+		{"timetz", "13:14:15-05:00", false, date(0, 1, 1, 13, 14, 15, 0, -5*3600)},
+		{"timestamp", "2021-03-04 13:14:15.123456", false, date(2021, 3, 4, 13, 14, 15, 123456000, 0)},
+		{"timestamptz", "2021-03-04 13:14:15.5+02:00", false, date(2021, 3, 4, 13, 14, 15, 500000000, 2*3600)},
+
+		{"sec_interval", "3.500", false, 3500 * time.Millisecond},
+		{"sec_interval", "-0.001", false, -time.Millisecond},
+		// more digits than a float64 can hold
+		{"sec_interval", "9007199254.741", false, 9007199254741 * time.Millisecond},
+		{"day_interval", "2", false, 48 * time.Hour},
+		{"month_interval", "14", false, int64(14)},
+
+		{"blob", "00FF10", false, []byte{0x00, 0xff, 0x10}},
+		{"json", `{"a": [1, null]}`, true, []byte(`{"a": [1, null]}`)},
+		{"uuid", "65e3b1b0-9a4d-4f7c-8a8c-0c3bd0e1f1a2", true, "65e3b1b0-9a4d-4f7c-8a8c-0c3bd0e1f1a2"},
+		{"inet", "192.168.0.1/24", true, "192.168.0.1/24"},
+		{"varchar", "over 9000", true, "over 9000"},
+	}
+
+	for _, c := range cases {
+		actual, err := decode(c.tpe, c.value, c.quoted)
+		if err != nil {
+			t.Errorf("%s %q: %v", c.tpe, c.value, err)
+			continue
+		}
+		if !reflect.DeepEqual(actual, c.want) {
+			t.Errorf("%s %q:\n  got:  %#v\n  want: %#v", c.tpe, c.value, actual, c.want)
+		}
+	}
+}
+
+func Test_Decode_Invalid(t *testing.T) {
+	cases := []struct {
+		tpe   string
+		value string
+	}{
+		{"int", "one"},
+		{"bigint", "9223372036854775808"},
+		{"hugeint", "1.5"},
+		{"decimal", "1.2.3"},
+		{"double", "x"},
+		{"date", "2021-13-01"},
+		{"timestamp", "yesterday"},
+		{"sec_interval", "soon"},
+		// doesn't fit in a time.Duration
+		{"sec_interval", "9223372037.000"},
+		{"day_interval", "1.5"},
+		{"month_interval", "a year"},
+		{"blob", "0F0"},
+	}
+
+	for _, c := range cases {
+		if actual, err := decode(c.tpe, c.value, false); err == nil {
+			t.Errorf("%s %q: expected an error, got %#v", c.tpe, c.value, actual)
+		}
+	}
+}
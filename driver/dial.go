@@ -0,0 +1,119 @@
+package driver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"io/ioutil"
+	"net"
+	"strings"
+	"time"
+)
+
+func dial(config Config) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: time.Second * 5}
+	if config.Socket != "" {
+		return dialUnix(dialer, config.Socket)
+	}
+
+	if config.TLS == nil {
+		return dialer.Dial("tcp", config.Host)
+	}
+
+	tlsConfig, err := config.TLS.build(config.Host)
+	if err != nil {
+		return nil, err
+	}
+	return tls.DialWithDialer(dialer, "tcp", config.Host, tlsConfig)
+}
+
+// The server expects a '0' byte before it sends its challenge, after that,
+// it's the same protocol as over TCP
+func dialUnix(dialer *net.Dialer, path string) (net.Conn, error) {
+	socket, err := dialer.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	socket.SetDeadline(time.Now().Add(time.Second * 5))
+	if _, err := socket.Write([]byte("0")); err != nil {
+		socket.Close()
+		return nil, err
+	}
+	return socket, nil
+}
+
+func (t *TLSConfig) build(host string) (*tls.Config, error) {
+	serverName := t.ServerName
+	if serverName == "" {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			serverName = h
+		} else {
+			serverName = host
+		}
+	}
+
+	config := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, detailedDriverError("failed to read TLS CA file", err.Error())
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, detailedDriverError("no certificate found in TLS CA file", t.CAFile)
+		}
+		config.RootCAs = pool
+	}
+
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, detailedDriverError("failed to load TLS client certificate", err.Error())
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if t.CertHash != "" {
+		expected, err := parseCertHash(t.CertHash)
+		if err != nil {
+			return nil, err
+		}
+		// the pin replaces the normal chain verification
+		config.InsecureSkipVerify = true
+		config.VerifyPeerCertificate = func(certs [][]byte, _ [][]*x509.Certificate) error {
+			if len(certs) == 0 {
+				return driverError("server presented no TLS certificate")
+			}
+			actual := sha256.Sum256(certs[0])
+			if !bytes.HasPrefix(actual[:], expected) {
+				return detailedDriverError("server TLS certificate doesn't match certhash", hex.EncodeToString(actual[:]))
+			}
+			return nil
+		}
+	}
+
+	return config, nil
+}
+
+func parseCertHash(certHash string) ([]byte, error) {
+	if !strings.HasPrefix(certHash, "sha256:") {
+		return nil, detailedDriverError("certhash must start with sha256:", certHash)
+	}
+	digits := strings.ReplaceAll(certHash[7:], ":", "")
+	// we only compare whole bytes, silently ignoring the last digit would
+	// weaken the pin
+	if len(digits)%2 == 1 {
+		return nil, detailedDriverError("certhash must have an even number of hex digits", certHash)
+	}
+	expected, err := hex.DecodeString(digits)
+	if err != nil || len(expected) == 0 {
+		return nil, detailedDriverError("invalid certhash", certHash)
+	}
+	return expected, nil
+}
@@ -0,0 +1,215 @@
+package driver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A self-signed certificate for 127.0.0.1, the PEM file of which is its own CA
+type testCert struct {
+	tls    tls.Certificate
+	caFile string
+	hash   string
+}
+
+func newTestCert(t *testing.T, caFile string) testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "localhost"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(der)
+	return testCert{
+		tls:    tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key},
+		caFile: caFile,
+		hash:   "sha256:" + hex.EncodeToString(sum[:]),
+	}
+}
+
+// A TLS server which accepts any login (or replies with loginReply), just
+// enough of MAPI for Open
+func testTLSServer(t *testing.T, cert testCert, loginReply string) string {
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert.tls}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveTestLogins(l, loginReply, func(c net.Conn) {
+		// wait for the client to close the connection
+		readTestMessage(c)
+	})
+	return l.Addr().String()
+}
+
+// A server which accepts any login, after which serve plays the server's side
+// of the rest of the conversation
+func testServer(t *testing.T, serve func(c net.Conn)) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveTestLogins(l, "", serve)
+	return l.Addr().String()
+}
+
+func serveTestLogins(l net.Listener, loginReply string, serve func(c net.Conn)) {
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				writeTestMessage(c, "salt:mserver:9:SHA512:LIT:SHA512:sql=6:")
+				if _, err := readTestMessage(c); err != nil {
+					return
+				}
+				writeTestMessage(c, loginReply)
+				if loginReply == "" {
+					serve(c)
+				}
+			}()
+		}
+	}()
+}
+
+func writeTestMessage(w io.Writer, message string) {
+	header := make([]byte, 2)
+	binary.LittleEndian.PutUint16(header, uint16(len(message)<<1|1))
+	w.Write(append(header, message...))
+}
+
+func readTestMessage(r io.Reader) (string, error) {
+	var message strings.Builder
+	header := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return "", err
+		}
+		length := binary.LittleEndian.Uint16(header)
+		data := make([]byte, length>>1)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return "", err
+		}
+		message.Write(data)
+		if length&1 == 1 {
+			return message.String(), nil
+		}
+	}
+}
+
+func testOpen(config Config) error {
+	config.UserName, config.Password, config.Database = "monetdb", "monetdb", "db"
+	conn, err := Open(config)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+func testConnect(t *testing.T, config Config) Conn {
+	config.UserName, config.Password, config.Database = "monetdb", "monetdb", "db"
+	conn, err := Open(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func Test_TLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "msql-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cert := newTestCert(t, filepath.Join(dir, "ca.pem"))
+	other := newTestCert(t, filepath.Join(dir, "other.pem"))
+	host := testTLSServer(t, cert, "")
+
+	// CA verification
+	if err := testOpen(Config{Host: host, TLS: &TLSConfig{CAFile: cert.caFile}}); err != nil {
+		t.Errorf("CA: %v", err)
+	}
+	if err := testOpen(Config{Host: host, TLS: &TLSConfig{CAFile: other.caFile}}); err == nil {
+		t.Error("CA: expected a certificate signed by another CA to fail")
+	}
+
+	// certhash pinning, which doesn't need the CA
+	if err := testOpen(Config{Host: host, TLS: &TLSConfig{CertHash: cert.hash}}); err != nil {
+		t.Errorf("certhash: %v", err)
+	}
+	if err := testOpen(Config{Host: host, TLS: &TLSConfig{CertHash: cert.hash[:7+16]}}); err != nil {
+		t.Errorf("certhash prefix: %v", err)
+	}
+	if err := testOpen(Config{Host: host, TLS: &TLSConfig{CertHash: other.hash}}); err == nil {
+		t.Error("certhash: expected another certificate's hash to fail")
+	}
+	if err := testOpen(Config{Host: host, TLS: &TLSConfig{CertHash: cert.hash[:7+15]}}); err == nil {
+		t.Error("certhash: expected an odd number of digits to be rejected")
+	}
+
+	// a redirect keeps our TLS settings (the server it redirects to only
+	// speaks TLS)
+	redirect := testTLSServer(t, cert, "^mapi:monetdb://"+host+"/db\n")
+	if err := testOpen(Config{Host: redirect, TLS: &TLSConfig{CAFile: cert.caFile}}); err != nil {
+		t.Errorf("redirect: %v", err)
+	}
+}
+
+// The schema and role are names, which can need quoting (and mustn't be able
+// to add statements of their own)
+func Test_Open_QuotesSchemaAndRole(t *testing.T) {
+	received := make(chan string, 1)
+	host := testServer(t, func(c net.Conn) {
+		message, err := readTestMessage(c)
+		if err != nil {
+			return
+		}
+		received <- message
+		writeTestMessage(c, "&3 0\n&3 0\n")
+		readTestMessage(c)
+	})
+
+	err := testOpen(Config{Host: host, Schema: `My "Schema"`, Role: "admin; drop table users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `sset schema "My ""Schema"""; set role "admin; drop table users";`
+	if message := <-received; message != want {
+		t.Errorf("got:  %s\nwant: %s", message, want)
+	}
+}
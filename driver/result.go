@@ -9,11 +9,14 @@ import (
 
 type Result interface {
 	Types() []string
+	Tables() []string
 	Columns() []string
 	Lengths() []int
 	Meta() *Meta
 	Next() ([][]string, error)
+	NextValues() ([][]interface{}, error)
 	IsSimple() (bool, string)
+	SetNull(null string)
 	Rows() ([][]string, error)
 	Maps() ([]map[string]string, error)
 }
@@ -58,29 +61,60 @@ func NewMeta(data []byte) *Meta {
 	return meta
 }
 
+// What the server sends when it needs more input, and when it wants a file
+// read or written (followed by a line with the request), without the newline
+const (
+	PROMPT_MORE = "\x01\x02"
+	PROMPT_FILE = "\x01\x03"
+)
+
 func newResult(c Conn) (Result, error) {
-	data, fin, err := c.ReadFrame()
-	if err != nil {
-		return nil, err
-	}
+	first := !c.reply.started
+	c.reply.started = true
 
-	if len(data) > 0 && data[0] == '!' {
-		// strip out leading ! and trailing \n
-		return nil, monetDBError(string(data[1 : len(data)-1]))
-	}
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return nil, err
+		}
 
-	if len(data) == 0 {
-		return EmptyResult{}, nil
+		if line == nil {
+			// A reply with no response at all is still a result (of nothing), but
+			// only the first time we're asked for it.
+			if first {
+				return EmptyResult{}, nil
+			}
+			return nil, nil
+		}
+
+		if len(line) == 0 || line[0] == '[' || line[0] == '%' {
+			// rows or header of a previous result that wasn't fully read
+			continue
+		}
+
+		if line[0] == '!' {
+			return nil, readError(c, line)
+		}
+
+		return parseResponse(c, line)
 	}
+}
 
+func parseResponse(c Conn, data []byte) (Result, error) {
 	if bytes.HasPrefix(data, []byte("&3 ")) {
 		return OKResult{}, nil
 	}
 
+	if bytes.HasPrefix(data, []byte("&4 ")) {
+		// auto-commit changed (e.g. start transaction / commit): &4 t or &4 f
+		c.session.autoCommit = len(data) > 3 && data[3] == 't'
+		return OKResult{}, nil
+	}
+
 	meta := NewMeta(data)
 
 	if bytes.HasPrefix(data, []byte("&1 ")) {
-		return newQueryResult(c, data, fin, meta)
+		return newQueryResult(c, meta)
 	}
 
 	if bytes.HasPrefix(data, []byte("&2 ")) {
@@ -89,26 +123,45 @@ func newResult(c Conn) (Result, error) {
 
 	if bytes.HasPrefix(data, []byte("&5 ")) {
 		parts := bytes.SplitN(data[3:], []byte(" "), 2)
-		return PrepareResult{
-			id:           string(parts[0]),
-			SimpleResult: SimpleResult{meta: meta},
-		}, nil
+		return newPrepareResult(c, string(parts[0]), meta)
 	}
 
 	return nil, detailedDriverError("unknown response", string(data))
 }
 
+// An error can span multiple lines, each starting with a !
+func readError(c Conn, line []byte) error {
+	message := string(line[1:])
+	for {
+		b, err := c.peek()
+		if err != nil {
+			return err
+		}
+		if b != '!' {
+			return monetDBError(message)
+		}
+		line, err := c.ReadLine()
+		if err != nil {
+			return err
+		}
+		message += "\n" + string(line[1:])
+	}
+}
+
 type SimpleResult struct {
 	meta *Meta
 }
 
-func (r SimpleResult) Meta() *Meta                        { return r.meta }
-func (_ SimpleResult) Types() []string                    { return nil }
-func (_ SimpleResult) Lengths() []int                     { return nil }
-func (_ SimpleResult) Columns() []string                  { return nil }
-func (_ SimpleResult) Next() ([][]string, error)          { return nil, nil }
-func (_ SimpleResult) Rows() ([][]string, error)          { return nil, nil }
-func (_ SimpleResult) Maps() ([]map[string]string, error) { return nil, nil }
+func (r SimpleResult) Meta() *Meta                          { return r.meta }
+func (_ SimpleResult) Types() []string                      { return nil }
+func (_ SimpleResult) Tables() []string                     { return nil }
+func (_ SimpleResult) Lengths() []int                       { return nil }
+func (_ SimpleResult) Columns() []string                    { return nil }
+func (_ SimpleResult) Next() ([][]string, error)            { return nil, nil }
+func (_ SimpleResult) NextValues() ([][]interface{}, error) { return nil, nil }
+func (_ SimpleResult) Rows() ([][]string, error)            { return nil, nil }
+func (_ SimpleResult) SetNull(null string)                  {}
+func (_ SimpleResult) Maps() ([]map[string]string, error)   { return nil, nil }
 
 type EmptyResult struct{ SimpleResult }
 
@@ -119,10 +172,17 @@ type OKResult struct{ SimpleResult }
 func (r OKResult) IsSimple() (bool, string) { return true, "OK\n" }
 
 type PrepareResult struct {
-	id string
+	id      string
+	params  []Field
+	columns []Field
 	SimpleResult
 }
 
+// The server-side id of the prepared statement, used to exec / deallocate it
+func (r PrepareResult) Id() string {
+	return r.id
+}
+
 func (r PrepareResult) IsSimple() (bool, string) {
 	return true, fmt.Sprintf("OK, use: exec %s(...);\n", r.id)
 }
@@ -131,29 +191,50 @@ func (r PrepareResult) IsSimple() (bool, string) {
 // type of result.
 type QueryResult struct {
 	conn    Conn
-	fin     bool
+	done    bool
+	null    string
 	lengths []int
 	scratch []byte
 	types   []string
+	tables  []string
 	columns []string
 	meta    *Meta
-	buffer  bytes.Buffer
 }
 
-func newQueryResult(c Conn, data []byte, fin bool, meta *Meta) (Result, error) {
-	parts := bytes.SplitN(data, []byte("\n"), 6)
-
-	columnLine := string(parts[2])
-	columnLine = columnLine[2 : len(columnLine)-len(" # name")]
-	columns := strings.Split(columnLine, ",\t")
+func newQueryResult(c Conn, meta *Meta) (Result, error) {
+	var columns, types, tables, lengthStrings []string
+	for {
+		b, err := c.peek()
+		if err != nil {
+			return nil, err
+		}
+		if b != '%' {
+			break
+		}
 
-	typeLine := string(parts[3])
-	typeLine = typeLine[2 : len(typeLine)-len(" # type")]
-	types := strings.Split(typeLine, ",\t")
+		data, err := c.ReadLine()
+		if err != nil {
+			return nil, err
+		}
 
-	lengthLine := string(parts[4])
-	lengthLine = lengthLine[2 : len(lengthLine)-len(" # length")]
-	lengthStrings := strings.Split(lengthLine, ",\t")
+		// % value1,\tvalue2 # name
+		line := string(data)
+		hash := strings.LastIndex(line, " # ")
+		if hash == -1 {
+			return nil, detailedDriverError("invalid result header", line)
+		}
+		values := strings.Split(line[2:hash], ",\t")
+		switch line[hash+3:] {
+		case "name":
+			columns = values
+		case "type":
+			types = values
+		case "table_name":
+			tables = values
+		case "length":
+			lengthStrings = values
+		}
+	}
 
 	max := 0
 	lengths := make([]int, len(lengthStrings))
@@ -167,16 +248,13 @@ func newQueryResult(c Conn, data []byte, fin bool, meta *Meta) (Result, error) {
 	// try to reduce the amount we'll need to allocate for unquoting the strings
 	scratch := make([]byte, 3*max/2)
 
-	var buffer bytes.Buffer
-	buffer.Write(parts[5])
-
 	return &QueryResult{
 		conn:    c,
-		fin:     fin,
+		null:    "NULL",
 		types:   types,
+		tables:  tables,
 		columns: columns,
 		lengths: lengths,
-		buffer:  buffer,
 		meta:    meta,
 		scratch: scratch,
 	}, nil
@@ -184,10 +262,22 @@ func newQueryResult(c Conn, data []byte, fin bool, meta *Meta) (Result, error) {
 
 func (r *QueryResult) IsSimple() (bool, string) { return false, "" }
 
+// The string Next returns for NULL values (defaults to NULL). Unlike the
+// server's text, this lets callers tell a NULL apart from the string 'NULL'.
+func (r *QueryResult) SetNull(null string) {
+	r.null = null
+}
+
 func (r *QueryResult) Types() []string {
 	return r.types
 }
 
+// The (schema qualified) table of each column, e.g. sys.users. Computed
+// columns have a generated name like %1.
+func (r *QueryResult) Tables() []string {
+	return r.tables
+}
+
 func (r *QueryResult) Columns() []string {
 	return r.columns
 }
@@ -201,7 +291,7 @@ func (r *QueryResult) Meta() *Meta {
 }
 
 func (r *QueryResult) Rows() ([][]string, error) {
-	rows := make([][]string, 0, r.meta.RowCount)
+	rows := make([][]string, 0, r.rowCount())
 	for {
 		data, err := r.Next()
 		if err != nil {
@@ -216,7 +306,7 @@ func (r *QueryResult) Rows() ([][]string, error) {
 
 func (r *QueryResult) Maps() ([]map[string]string, error) {
 	columns := r.columns
-	rows := make([]map[string]string, 0, r.meta.RowCount)
+	rows := make([]map[string]string, 0, r.rowCount())
 
 	for {
 		data, err := r.Next()
@@ -236,68 +326,130 @@ func (r *QueryResult) Maps() ([]map[string]string, error) {
 	}
 }
 
-func (r *QueryResult) Next() ([][]string, error) {
-	if r.fin {
-		if r.buffer.Len() == 0 {
-			return nil, nil
-		}
-		return r.asRows(), nil
+// some responses (like the one to a prepare) have no meta
+func (r *QueryResult) rowCount() int {
+	if r.meta == nil {
+		return 0
 	}
+	return r.meta.RowCount
+}
 
-	data, fin, err := r.conn.ReadFrame()
-	if err != nil {
-		return nil, err
-	}
+// Returns the rows available from the data read so far, reading another frame
+// only when no complete row is available. This lets outputs stream the result.
+// Returns nil once all the rows of this result have been read.
+func (r *QueryResult) Next() ([][]string, error) {
+	var table [][]string
+	err := r.next(func(row []byte) error {
+		table = append(table, r.asRow(row))
+		return nil
+	})
+	return table, err
+}
 
-	r.fin = fin
-	r.buffer.Write(data)
-	rows := r.asRows()
-	if len(rows) == 0 {
-		if r.fin {
-			return nil, nil
+// Like Next, but each value is decoded into a Go value based on its column's
+// type (see decode). NULLs are returned as nil.
+func (r *QueryResult) NextValues() ([][]interface{}, error) {
+	var table [][]interface{}
+	err := r.next(func(row []byte) error {
+		values, err := r.asValues(row)
+		if err != nil {
+			return err
 		}
-		return r.Next()
-	}
-	return rows, nil
+		table = append(table, values)
+		return nil
+	})
+	return table, err
 }
 
-func (r *QueryResult) asRows() [][]string {
-	data := r.buffer.Bytes()
-	// a frame could start with the newline from the last row, ignore it
-	// else our split will put an empty row at the front
-	if data[0] == '\n' {
-		data = data[1:]
-	}
-	rows := bytes.Split(data, []byte("\n"))
-
-	var partialRow []byte
-	lastRowIndex := len(rows) - 1
-	// The last row isn't a complete row. We have to ignore it in this call to
-	// asRows, but we append it to our buffer for it to be merged with data from
-	// the next ReadFrame (on the next call to our Next() function above)
-	if !bytes.HasSuffix(rows[lastRowIndex], []byte("\t]")) {
-		partialRow = rows[lastRowIndex]
-		rows = rows[:lastRowIndex]
+// Calls fn for each complete row available, reading frames until there's at
+// least one (or the result is done).
+func (r *QueryResult) next(fn func(row []byte) error) error {
+	for !r.done {
+		count, err := r.asRows(fn)
+		if count > 0 || err != nil {
+			return err
+		}
+		if r.done {
+			break
+		}
+		if err := r.conn.fill(); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// Consumes every complete row currently in the reply buffer. The reply can
+// contain other responses after our rows, we stop at the first line that isn't
+// a row and leave it for the next call to NextResult.
+func (r *QueryResult) asRows(fn func(row []byte) error) (int, error) {
+	reply := r.conn.reply
+	count := 0
+	for {
+		data := reply.buffer.Bytes()
+		if len(data) == 0 {
+			r.done = reply.fin
+			return count, nil
+		}
+		if data[0] != '[' {
+			r.done = true
+			return count, nil
+		}
 
-	table := make([][]string, len(rows))
-	for i, row := range rows {
-		// 2 : len()-2   to strip out the leading and trailing '[\t' and '\t]'
-		values := strings.Split(string(row[2:len(row)-2]), ",\t")
-		for i, value := range values {
-			if value[0] == '"' {
-				values[i] = unquote(strings.Trim(value, "\""), r.scratch[:0])
+		end := bytes.IndexByte(data, '\n')
+		if end == -1 {
+			if !reply.fin {
+				// The last row isn't complete. It'll be completed by the data from
+				// the next frame
+				return count, nil
 			}
+			end = len(data)
+		}
+
+		if err := fn(data[:end]); err != nil {
+			return count, err
+		}
+		count += 1
+		if end == len(data) {
+			reply.buffer.Reset()
+		} else {
+			reply.buffer.Next(end + 1)
 		}
-		table[i] = values
 	}
+}
+
+func (r *QueryResult) asRow(row []byte) []string {
+	values := splitRow(row)
+	for i, value := range values {
+		if value[0] == '"' {
+			values[i] = unquote(strings.Trim(value, "\""), r.scratch[:0])
+		} else if value == "NULL" {
+			values[i] = r.null
+		}
+	}
+	return values
+}
 
-	r.buffer.Reset()
-	if partialRow != nil {
-		r.buffer.Write(partialRow)
+func (r *QueryResult) asValues(row []byte) ([]interface{}, error) {
+	fields := splitRow(row)
+	values := make([]interface{}, len(fields))
+	for i, field := range fields {
+		quoted := field[0] == '"'
+		if quoted {
+			field = unquote(strings.Trim(field, "\""), r.scratch[:0])
+		}
+		value, err := decode(r.types[i], field, quoted)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
 	}
+	return values, nil
+}
 
-	return table
+func splitRow(row []byte) []string {
+	// 2 : len()-2   to strip out the leading and trailing '[\t' and '\t]'
+	return strings.Split(string(row[2:len(row)-2]), ",\t")
 }
 
 func unquote(s string, buf []byte) string {
@@ -0,0 +1,144 @@
+package driver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A server-side prepared statement. Can be executed any number of times, but
+// must be closed to free the server-side resources.
+type Stmt struct {
+	id      string
+	conn    Conn
+	params  []Field
+	columns []Field
+}
+
+// Describes a parameter or result column of a prepared statement, as returned
+// by the server when the statement is prepared.
+type Field struct {
+	Type   string
+	Digits int
+	Scale  int
+	Schema string
+	Table  string
+	Column string
+}
+
+func (c Conn) Prepare(sql string) (*Stmt, error) {
+	if err := c.Send("sprepare ", sql, ";"); err != nil {
+		return nil, err
+	}
+	result, err := newResult(c)
+	if err != nil {
+		return nil, err
+	}
+	prepared, ok := result.(PrepareResult)
+	if !ok {
+		return nil, driverError("invalid prepare response")
+	}
+
+	return &Stmt{
+		conn:    c,
+		id:      prepared.id,
+		params:  prepared.params,
+		columns: prepared.columns,
+	}, nil
+}
+
+// The server-side id, usable directly in SQL: exec ID(...)
+func (s *Stmt) Id() string {
+	return s.id
+}
+
+func (s *Stmt) Params() []Field {
+	return s.params
+}
+
+func (s *Stmt) Columns() []Field {
+	return s.columns
+}
+
+// Executes the statement, returning the first result of the reply (more can
+// be read using the connection's NextResult)
+func (s *Stmt) Exec(args ...interface{}) (Result, error) {
+	if err := s.Send(args...); err != nil {
+		return nil, err
+	}
+	return newResult(s.conn)
+}
+
+// Like Exec, but every result of the reply is left to be read using the
+// connection's NextResult (e.g. by outputs.Render)
+func (s *Stmt) Send(args ...interface{}) error {
+	if len(args) != len(s.params) {
+		return driverError(fmt.Sprintf("expected %d arguments, got %d", len(s.params), len(args)))
+	}
+
+	encoded := make([]string, len(args))
+	for i, arg := range args {
+		value, err := encodeAs(s.params[i].Type, arg)
+		if err != nil {
+			return err
+		}
+		encoded[i] = value
+	}
+	return s.conn.Send("sexec ", s.id, "(", strings.Join(encoded, ", "), ");")
+}
+
+// Deallocates the statement on the server. Any unread result of a previous
+// Exec is discarded.
+func (s *Stmt) Close() error {
+	if err := s.conn.Send("sdeallocate ", s.id, ";"); err != nil {
+		return err
+	}
+	_, err := newResult(s.conn)
+	return err
+}
+
+// The reply to a prepare is a table with 1 row per result column followed by
+// 1 row per parameter. Parameters are the ones without a column name.
+func newPrepareResult(c Conn, id string, meta *Meta) (Result, error) {
+	result, err := newQueryResult(c, meta)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := result.Rows()
+	if err != nil {
+		return nil, err
+	}
+
+	prepared := PrepareResult{
+		id:           id,
+		SimpleResult: SimpleResult{meta: meta},
+	}
+
+	for _, row := range rows {
+		if len(row) < 6 {
+			return nil, detailedDriverError("invalid prepare response", strings.Join(row, ","))
+		}
+		field := Field{
+			Type:   row[0],
+			Schema: nullable(row[3]),
+			Table:  nullable(row[4]),
+			Column: nullable(row[5]),
+		}
+		field.Digits, _ = strconv.Atoi(row[1])
+		field.Scale, _ = strconv.Atoi(row[2])
+		if field.Column == "" {
+			prepared.params = append(prepared.params, field)
+		} else {
+			prepared.columns = append(prepared.columns, field)
+		}
+	}
+	return prepared, nil
+}
+
+func nullable(value string) string {
+	if value == "NULL" {
+		return ""
+	}
+	return value
+}
@@ -0,0 +1,164 @@
+package driver
+
+import (
+	"math"
+	"math/big"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func Test_EncodeAs(t *testing.T) {
+	at := time.Date(2021, 3, 4, 13, 14, 15, 500000000, time.FixedZone("", 2*3600))
+	hugeint, _ := new(big.Int).SetString("-170141183460469231731687303715884105727", 10)
+
+	cases := []struct {
+		tpe   string
+		value interface{}
+		want  string
+	}{
+		{"int", nil, "NULL"},
+		{"int", 42, "42"},
+		{"tinyint", int8(-8), "-8"},
+		{"smallint", int16(16), "16"},
+		{"int", int32(-32), "-32"},
+		{"bigint", int64(9223372036854775807), "9223372036854775807"},
+		{"int", uint(1), "1"},
+		{"bigint", uint64(18446744073709551615), "18446744073709551615"},
+		{"hugeint", hugeint, "-170141183460469231731687303715884105727"},
+		{"real", float32(1.5), "1.5"},
+		{"double", -2.25e10, "-2.25e+10"},
+		{"boolean", true, "true"},
+		{"boolean", false, "false"},
+		{"decimal", Decimal{Unscaled: big.NewInt(-5), Scale: 2}, "-0.05"},
+		{"decimal", &Decimal{Unscaled: big.NewInt(10025), Scale: 2}, "100.25"},
+		{"blob", []byte{0x00, 0xff}, "blob '00ff'"},
+		{"sec_interval", 3500 * time.Millisecond, "interval '3.500' second"},
+
+		{"date", at, "date '2021-03-04'"},
+		{"time", at, "time '13:14:15.5'"},
+		{"timetz", at, "timetz '13:14:15.5+02:00'"},
+		{"timestamp", at, "timestamp '2021-03-04 13:14:15.5'"},
+		{"timestamptz", at, "timestamptz '2021-03-04 13:14:15.5+02:00'"},
+
+		// strings are quoted, with their quotes and backslashes escaped
+		{"varchar", "it's", `'it\'s'`},
+		{"clob", `C:\data`, `'C:\\data'`},
+		{"varchar", "NULL", "'NULL'"},
+		{"json", `{"a": 1}`, `'{"a": 1}'`},
+
+		// unless they're a valid value of the parameter's type
+		{"int", "-42", "-42"},
+		{"hugeint", "170141183460469231731687303715884105727", "170141183460469231731687303715884105727"},
+		{"decimal", "+1.50", "1.50"},
+		{"double", "1e3", "1000"},
+		{"boolean", "true", "true"},
+		{"date", "2021-03-04", "date '2021-03-04'"},
+		{"timestamptz", "2021-03-04 13:14:15+02:00", "timestamptz '2021-03-04 13:14:15+02:00'"},
+		{"sec_interval", "3.5", "interval '3.5' second"},
+		{"month_interval", "14", "interval '14' month"},
+		{"int", "1); drop table users; --", `'1); drop table users; --'`},
+		{"double", "NaN", "'NaN'"},
+		{"boolean", "yes", "'yes'"},
+		{"date", "it's", `date 'it\'s'`},
+	}
+
+	for _, c := range cases {
+		actual, err := encodeAs(c.tpe, c.value)
+		if err != nil {
+			t.Errorf("%s %#v: %v", c.tpe, c.value, err)
+			continue
+		}
+		if actual != c.want {
+			t.Errorf("%s %#v:\n  got:  %s\n  want: %s", c.tpe, c.value, actual, c.want)
+		}
+	}
+
+	invalid := []struct {
+		tpe   string
+		value interface{}
+	}{
+		{"int", struct{}{}},
+		{"varchar", at},
+		{"int", at},
+		{"double", math.NaN()},
+		{"double", math.Inf(1)},
+		{"real", float32(math.Inf(-1))},
+	}
+	for _, c := range invalid {
+		if actual, err := encodeAs(c.tpe, c.value); err == nil {
+			t.Errorf("%s %#v: expected an error, got %s", c.tpe, c.value, actual)
+		}
+	}
+}
+
+// The reply to "prepare select name from sys.users where id = ? and balance = ?"
+const testPrepareReply = "&5 7 3 6 3\n" +
+	"% .prepare,\t.prepare,\t.prepare,\t.prepare,\t.prepare,\t.prepare # table_name\n" +
+	"% type,\tdigits,\tscale,\tschema,\ttable,\tcolumn # name\n" +
+	"% varchar,\tint,\tint,\tstr,\tstr,\tstr # type\n" +
+	"% 7,\t4,\t1,\t3,\t5,\t4 # length\n" +
+	"[ \"varchar\",\t1024,\t0,\t\"sys\",\t\"users\",\t\"name\"\t]\n" +
+	"[ \"int\",\t32,\t0,\tNULL,\tNULL,\tNULL\t]\n" +
+	"[ \"decimal\",\t18,\t3,\tNULL,\tNULL,\tNULL\t]\n"
+
+func Test_Prepare(t *testing.T) {
+	received := make(chan string, 10)
+	host := testServer(t, func(c net.Conn) {
+		defer close(received)
+		for _, reply := range []string{testPrepareReply, "&2 1 -1 0 0 0 0\n", "&3 0\n"} {
+			message, err := readTestMessage(c)
+			if err != nil {
+				return
+			}
+			received <- message
+			writeTestMessage(c, reply)
+		}
+	})
+
+	conn := testConnect(t, Config{Host: host})
+	defer conn.Close()
+
+	stmt, err := conn.Prepare("select name from sys.users where id = ? and balance = ?")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message := <-received; message != "sprepare select name from sys.users where id = ? and balance = ?;" {
+		t.Errorf("prepare sent %q", message)
+	}
+	if stmt.Id() != "7" {
+		t.Errorf("expected the statement's id to be 7, got %s", stmt.Id())
+	}
+
+	wantColumns := []Field{{Type: "varchar", Digits: 1024, Schema: "sys", Table: "users", Column: "name"}}
+	if !reflect.DeepEqual(stmt.Columns(), wantColumns) {
+		t.Errorf("columns:\n  got:  %#v\n  want: %#v", stmt.Columns(), wantColumns)
+	}
+	wantParams := []Field{{Type: "int", Digits: 32}, {Type: "decimal", Digits: 18, Scale: 3}}
+	if !reflect.DeepEqual(stmt.Params(), wantParams) {
+		t.Errorf("params:\n  got:  %#v\n  want: %#v", stmt.Params(), wantParams)
+	}
+
+	if _, err := stmt.Exec(1); err == nil {
+		t.Errorf("expected an error for a missing argument")
+	}
+
+	result, err := stmt.Exec(1, "2.50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message := <-received; message != "sexec 7(1, 2.50);" {
+		t.Errorf("exec sent %q", message)
+	}
+	if meta := result.Meta(); meta == nil || meta.RowCount != 1 {
+		t.Errorf("got %#v, want 1 affected row", result)
+	}
+
+	if err := stmt.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if message := <-received; message != "sdeallocate 7;" {
+		t.Errorf("close sent %q", message)
+	}
+}
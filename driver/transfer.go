@@ -0,0 +1,248 @@
+package driver
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Uploads are sent in chunks, after each one the server says whether it wants
+// more
+const UPLOAD_CHUNK = 1024 * 1024
+
+// Reads and writes the files of COPY ... ON CLIENT statements, e.g. data.csv
+// in COPY INTO t FROM 'data.csv' ON CLIENT (upload) or in
+// COPY SELECT * FROM t INTO 'data.csv' ON CLIENT (download). The name is what
+// the statement says, and comes from the server, so it shouldn't be trusted.
+// text is false for COPY BINARY.
+// Returning an error refuses the request, the statement then fails with it.
+type FileTransfer interface {
+	Upload(name string, text bool) (io.ReadCloser, error)
+	Download(name string, text bool) (io.WriteCloser, error)
+}
+
+// A FileTransfer which only allows the files of a directory (including those
+// of its sub-directories). Relative names are relative to the directory.
+type DirectoryTransfer struct {
+	Dir string
+}
+
+var ErrOutsideDirectory = errors.New("file is outside the file transfer directory")
+
+func (d DirectoryTransfer) Upload(name string, text bool) (io.ReadCloser, error) {
+	path, err := d.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Creates (or truncates) the file
+func (d DirectoryTransfer) Download(name string, text bool) (io.WriteCloser, error) {
+	path, err := d.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Create(path)
+}
+
+// Resolves symlinks, so that a link can't point outside the directory either
+func (d DirectoryTransfer) path(name string) (string, error) {
+	dir, err := filepath.Abs(d.Dir)
+	if err != nil {
+		return "", err
+	}
+	if dir, err = filepath.EvalSymlinks(dir); err != nil {
+		return "", err
+	}
+
+	path := name
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if os.IsNotExist(err) {
+		// a file being downloaded doesn't have to exist, its directory does
+		var parent string
+		if parent, err = filepath.EvalSymlinks(filepath.Dir(path)); err == nil {
+			resolved = filepath.Join(parent, filepath.Base(path))
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	relative, err := filepath.Rel(dir, resolved)
+	if err != nil || relative == ".." || strings.HasPrefix(relative, ".."+string(filepath.Separator)) {
+		return "", ErrOutsideDirectory
+	}
+	return resolved, nil
+}
+
+// Handles the request which followed the server's PROMPT_FILE, one of
+// "r OFFSET NAME" (upload a text file, from line OFFSET), "rb NAME" (upload a
+// binary file), "w NAME" (download a text file) or "wb NAME" (download a binary
+// file). Once done, the server carries on with the reply, in a new message.
+func (c Conn) transferFile(request string) error {
+	// the request is the last thing of its message
+	if err := c.discard(); err != nil {
+		return err
+	}
+
+	var err error
+	switch {
+	case strings.HasPrefix(request, "r "):
+		parts := strings.SplitN(request[2:], " ", 2)
+		offset, convErr := strconv.Atoi(parts[0])
+		if convErr != nil || len(parts) != 2 {
+			err = c.refuse("invalid file transfer request: " + request)
+		} else {
+			err = c.upload(parts[1], true, offset)
+		}
+	case strings.HasPrefix(request, "rb "):
+		err = c.upload(request[3:], false, 0)
+	case strings.HasPrefix(request, "w "):
+		err = c.download(request[2:], true)
+	case strings.HasPrefix(request, "wb "):
+		err = c.download(request[3:], false)
+	default:
+		err = c.refuse("invalid file transfer request: " + request)
+	}
+
+	c.reply.fin = false
+	return err
+}
+
+// Anything but an empty line refuses the request (and is the error the
+// statement fails with)
+func (c Conn) refuse(message string) error {
+	return c.write([]string{strings.ReplaceAll(message, "\n", " "), "\n"})
+}
+
+func (c Conn) upload(name string, text bool, offset int) error {
+	if c.transfer == nil {
+		return c.refuse("file transfer is not enabled")
+	}
+	file, err := c.transfer.Upload(name, text)
+	if err != nil {
+		return c.refuse(err.Error())
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 65536)
+	// OFFSET 1 (like 0) is the first line
+	for line := 1; line < offset; line++ {
+		if err := skipLine(reader); err == io.EOF {
+			break
+		} else if err != nil {
+			return c.refuse(err.Error())
+		}
+	}
+
+	chunk := make([]byte, UPLOAD_CHUNK)
+	// an empty line accepts the request
+	chunk[0] = '\n'
+	n := 1
+	for {
+		read, err := io.ReadFull(reader, chunk[n:])
+		n += read
+		eof := err == io.EOF || err == io.ErrUnexpectedEOF
+		if err != nil && !eof {
+			// It's too late to tell the server, which would take what it got as
+			// the whole file. Closing the connection aborts the statement.
+			c.Conn.Close()
+			return detailedDriverError("file transfer failed", err.Error())
+		}
+		if n > 0 {
+			if err := c.write([]string{string(chunk[:n])}); err != nil {
+				return err
+			}
+			more, err := c.uploadPrompt()
+			if err != nil || !more {
+				// the server has all it wants (e.g. the statement's RECORDS)
+				return err
+			}
+		}
+		if eof {
+			break
+		}
+		n = 0
+	}
+
+	// an empty message ends the file
+	c.reply.uploaded = true
+	return c.write(nil)
+}
+
+// Whether the server wants more of the file being uploaded
+func (c Conn) uploadPrompt() (bool, error) {
+	message, err := c.readMessageString()
+	if err != nil {
+		return false, err
+	}
+	switch strings.TrimSuffix(message, "\n") {
+	case PROMPT_MORE:
+		return true, nil
+	case PROMPT_FILE:
+		return false, nil
+	}
+	return false, detailedDriverError("unexpected file transfer response", message)
+}
+
+// Skips the rest of the line, however long it is
+func skipLine(reader *bufio.Reader) error {
+	for {
+		_, err := reader.ReadSlice('\n')
+		if err != bufio.ErrBufferFull {
+			return err
+		}
+	}
+}
+
+// The file comes in messages, until an empty one. All of it has to be read,
+// even when it can't be written, to get to the rest of the reply.
+func (c Conn) download(name string, text bool) error {
+	if c.transfer == nil {
+		return c.refuse("file transfer is not enabled")
+	}
+	file, err := c.transfer.Download(name, text)
+	if err != nil {
+		return c.refuse(err.Error())
+	}
+	if err := c.write([]string{"\n"}); err != nil {
+		file.Close()
+		return err
+	}
+
+	var failed error
+	length := 0
+	for {
+		data, fin, err := c.ReadFrame()
+		if err != nil {
+			file.Close()
+			return err
+		}
+		length += len(data)
+		if failed == nil && len(data) > 0 {
+			_, failed = file.Write(data)
+		}
+		if fin {
+			if length == 0 {
+				break
+			}
+			length = 0
+		}
+	}
+
+	if err := file.Close(); err != nil && failed == nil {
+		failed = err
+	}
+	if failed != nil {
+		return detailedDriverError("file transfer failed", failed.Error())
+	}
+	return nil
+}
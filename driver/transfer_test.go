@@ -0,0 +1,274 @@
+package driver
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testTransferDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "msql-transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "sub", "data.csv"), []byte("1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func Test_DirectoryTransfer_Path(t *testing.T) {
+	dir := testTransferDir(t)
+	defer os.RemoveAll(dir)
+	outside := testTransferDir(t)
+	defer os.RemoveAll(outside)
+
+	if err := os.Symlink(filepath.Join(outside, "sub", "data.csv"), filepath.Join(dir, "file-link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "dir-link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(dir, "sub"), filepath.Join(dir, "inside-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	transfer := DirectoryTransfer{Dir: dir}
+	cases := []struct {
+		name string
+		// "" when the file is outside the directory
+		want string
+	}{
+		{"sub/data.csv", "sub/data.csv"},
+		{"./sub/../sub/data.csv", "sub/data.csv"},
+		{"new.csv", "new.csv"},
+		{"inside-link/data.csv", "sub/data.csv"},
+		{filepath.Join(dir, "sub", "data.csv"), "sub/data.csv"},
+
+		{"../data.csv", ""},
+		{"sub/../../data.csv", ""},
+		{"..", ""},
+		{filepath.Join(outside, "sub", "data.csv"), ""},
+		{"/etc/passwd", ""},
+		{"file-link", ""},
+		{"dir-link/sub/data.csv", ""},
+		{"dir-link/new.csv", ""},
+	}
+
+	resolved, err := filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range cases {
+		path, err := transfer.path(c.name)
+		if c.want == "" {
+			if err != ErrOutsideDirectory {
+				t.Errorf("%s: got %q (%v), want ErrOutsideDirectory", c.name, path, err)
+			}
+			continue
+		}
+		if want := filepath.Join(resolved, c.want); err != nil || path != want {
+			t.Errorf("%s: got %q (%v), want %q", c.name, path, err, want)
+		}
+	}
+
+	// the directory of a download has to exist
+	if _, err := transfer.Download("missing/new.csv", true); !os.IsNotExist(err) {
+		t.Errorf("download into a missing directory: got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Error("download into a missing directory: the directory was created")
+	}
+}
+
+// Plays the server's side of a COPY ... ON CLIENT, which asks for a file with
+// request, and sends what it got (and read after it) to received
+func testTransferServer(t *testing.T, request string, serve func(c net.Conn, received chan string)) (string, chan string) {
+	received := make(chan string, 10)
+	host := testServer(t, func(c net.Conn) {
+		defer close(received)
+		if _, err := readTestMessage(c); err != nil {
+			return
+		}
+		writeTestMessage(c, PROMPT_FILE+"\n"+request+"\n")
+		serve(c, received)
+	})
+	return host, received
+}
+
+func testCopyOnClient(t *testing.T, host string, dir string) Result {
+	conn := testConnect(t, Config{Host: host, FileTransfer: DirectoryTransfer{Dir: dir}})
+	defer conn.Close()
+	if err := conn.Send("s", "copy into t from 'data.csv' on client", ";"); err != nil {
+		t.Fatal(err)
+	}
+	result, err := conn.NextResult()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func Test_Transfer_UploadText(t *testing.T) {
+	dir := testTransferDir(t)
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "data.csv"), []byte("1\n2\n3\n4"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	host, received := testTransferServer(t, "r 3 data.csv", func(c net.Conn, received chan string) {
+		message, _ := readTestMessage(c)
+		received <- message
+		writeTestMessage(c, PROMPT_MORE+"\n")
+		// the empty message which ends the file
+		message, _ = readTestMessage(c)
+		received <- message
+		// acknowledged, in its own message, before the reply
+		writeTestMessage(c, PROMPT_MORE+"\n")
+		writeTestMessage(c, "&2 2 -1 0 0 0 0\n")
+		// the acknowledgement isn't answered
+		if message, err := readTestMessage(c); err == nil {
+			received <- message
+		}
+	})
+
+	result := testCopyOnClient(t, host, dir)
+	if meta := result.Meta(); meta == nil || meta.RowCount != 2 {
+		t.Errorf("got %#v, want 2 affected rows", result)
+	}
+	// an empty line accepts the request, the file starts at line 3
+	if message := <-received; message != "\n3\n4" {
+		t.Errorf("got %q", message)
+	}
+	if message := <-received; message != "" {
+		t.Errorf("expected the empty message which ends the file, got %q", message)
+	}
+	if message, ok := <-received; ok {
+		t.Errorf("expected nothing after the upload, got %q", message)
+	}
+}
+
+func Test_Transfer_UploadBinary(t *testing.T) {
+	dir := testTransferDir(t)
+	defer os.RemoveAll(dir)
+	data := []byte{0, 1, '\n', 2, 255}
+	if err := ioutil.WriteFile(filepath.Join(dir, "data.bin"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	host, received := testTransferServer(t, "rb data.bin", func(c net.Conn, received chan string) {
+		message, _ := readTestMessage(c)
+		received <- message
+		writeTestMessage(c, PROMPT_MORE+"\n")
+		message, _ = readTestMessage(c)
+		received <- message
+		// acknowledged in the same message as the reply
+		writeTestMessage(c, PROMPT_MORE+"\n&2 1 -1 0 0 0 0\n")
+		if message, err := readTestMessage(c); err == nil {
+			received <- message
+		}
+	})
+
+	result := testCopyOnClient(t, host, dir)
+	if meta := result.Meta(); meta == nil || meta.RowCount != 1 {
+		t.Errorf("got %#v, want 1 affected row", result)
+	}
+	if message := <-received; message != "\n"+string(data) {
+		t.Errorf("got %q", message)
+	}
+	if message := <-received; message != "" {
+		t.Errorf("expected the empty message which ends the file, got %q", message)
+	}
+	if message, ok := <-received; ok {
+		t.Errorf("expected nothing after the upload, got %q", message)
+	}
+}
+
+// The server can stop an upload early (e.g. it has the RECORDS it wanted), the
+// rest of the file isn't sent
+func Test_Transfer_UploadStopped(t *testing.T) {
+	dir := testTransferDir(t)
+	defer os.RemoveAll(dir)
+	data := bytes.Repeat([]byte("a line\n"), UPLOAD_CHUNK/7+10)
+	if err := ioutil.WriteFile(filepath.Join(dir, "data.csv"), data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	host, received := testTransferServer(t, "r 0 data.csv", func(c net.Conn, received chan string) {
+		message, _ := readTestMessage(c)
+		received <- message
+		writeTestMessage(c, PROMPT_FILE+"\n")
+		writeTestMessage(c, "&2 5 -1 0 0 0 0\n")
+		// nothing else should come until the client hangs up
+		if message, err := readTestMessage(c); err == nil {
+			received <- message
+		}
+	})
+
+	result := testCopyOnClient(t, host, dir)
+	if meta := result.Meta(); meta == nil || meta.RowCount != 5 {
+		t.Errorf("got %#v, want 5 affected rows", result)
+	}
+	if message := <-received; len(message) != UPLOAD_CHUNK || message[0] != '\n' || message[1:] != string(data[:UPLOAD_CHUNK-1]) {
+		t.Errorf("expected the first chunk, got %d bytes", len(message))
+	}
+	if message, ok := <-received; ok {
+		t.Errorf("expected nothing after the server stopped the upload, got %q", message)
+	}
+}
+
+func Test_Transfer_Download(t *testing.T) {
+	dir := testTransferDir(t)
+	defer os.RemoveAll(dir)
+
+	host, received := testTransferServer(t, "w sub/out.csv", func(c net.Conn, received chan string) {
+		message, _ := readTestMessage(c)
+		received <- message
+		writeTestMessage(c, "1,a\n")
+		writeTestMessage(c, "2,b\n")
+		writeTestMessage(c, "")
+		writeTestMessage(c, "&2 2 -1 0 0 0 0\n")
+	})
+
+	result := testCopyOnClient(t, host, dir)
+	if meta := result.Meta(); meta == nil || meta.RowCount != 2 {
+		t.Errorf("got %#v, want 2 affected rows", result)
+	}
+	if message := <-received; message != "\n" {
+		t.Errorf("expected an empty line accepting the request, got %q", message)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "sub", "out.csv"))
+	if err != nil || string(data) != "1,a\n2,b\n" {
+		t.Errorf("got %q (%v)", data, err)
+	}
+}
+
+func Test_Transfer_Refused(t *testing.T) {
+	dir := testTransferDir(t)
+	defer os.RemoveAll(dir)
+
+	host, received := testTransferServer(t, "w ../out.csv", func(c net.Conn, received chan string) {
+		message, _ := readTestMessage(c)
+		received <- message
+		writeTestMessage(c, "!"+strings.TrimSuffix(message, "\n")+"\n")
+	})
+
+	conn := testConnect(t, Config{Host: host, FileTransfer: DirectoryTransfer{Dir: dir}})
+	defer conn.Close()
+	if err := conn.Send("s", "copy select 1 into '../out.csv' on client", ";"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.NextResult(); err == nil || !strings.Contains(err.Error(), ErrOutsideDirectory.Error()) {
+		t.Errorf("got %v, want the refusal", err)
+	}
+	if message := <-received; message != ErrOutsideDirectory.Error()+"\n" {
+		t.Errorf("got %q", message)
+	}
+}
@@ -5,7 +5,8 @@ go 1.14
 require (
 	github.com/jessevdk/go-flags v1.4.0
 	github.com/knz/go-libedit v1.10.1
+	github.com/mattn/go-runewidth v0.0.7
 	github.com/olekukonko/tablewriter v0.0.4
 	github.com/sirupsen/logrus v1.6.0
-	golang.org/x/sys v0.0.0-20200806125547-5acd03effb82 // indirect
+	golang.org/x/sys v0.0.0-20200806125547-5acd03effb82
 )
@@ -0,0 +1,188 @@
+package lexer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	WORD       = iota // keywords and unquoted identifiers
+	IDENTIFIER        // a "quoted" identifier
+	STRING            // '...', E'...', R'...' or X'...'
+	NUMBER
+	OPERATOR // punctuation and operators, including ; ( ) , and .
+	COMMENT  // -- to the end of the line, or /* ... */
+	SPACE
+	BODY // { ... }, the body of a function in another language (e.g. python)
+	META // a \ command (handled by msql, not the server), to the end of the line
+)
+
+type Token struct {
+	Kind int
+	Text string
+
+	// Of the token's first byte in the input
+	Offset int
+
+	// The input ended before the token did (e.g. in the middle of a string),
+	// only ever true for the last token
+	Unterminated bool
+}
+
+func (t Token) Is(word string) bool {
+	return t.Kind == WORD && strings.EqualFold(t.Text, word)
+}
+
+// Spaces and comments
+func (t Token) Insignificant() bool {
+	return t.Kind == SPACE || t.Kind == COMMENT
+}
+
+// operators which are 2 characters
+var operators = []string{"<=", ">=", "<>", "!=", "||", "::"}
+
+// Never fails: input which isn't valid SQL still gets tokens (the server will
+// tell the user what's wrong with it).
+func Tokenize(input string) []Token {
+	var tokens []Token
+	for offset := 0; offset < len(input); {
+		kind, end, unterminated := next(input, offset)
+		tokens = append(tokens, Token{
+			Kind:         kind,
+			Text:         input[offset:end],
+			Offset:       offset,
+			Unterminated: unterminated,
+		})
+		offset = end
+	}
+	return tokens
+}
+
+// The kind and end of the token which starts at offset
+func next(input string, offset int) (int, int, bool) {
+	rest := input[offset:]
+	r, size := utf8.DecodeRuneInString(rest)
+
+	switch {
+	case unicode.IsSpace(r):
+		end := offset + size
+		for end < len(input) {
+			r, size := utf8.DecodeRuneInString(input[end:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			end += size
+		}
+		return SPACE, end, false
+	case strings.HasPrefix(rest, "--"):
+		if nl := strings.IndexByte(rest, '\n'); nl != -1 {
+			return COMMENT, offset + nl, false
+		}
+		return COMMENT, len(input), false
+	case strings.HasPrefix(rest, "/*"):
+		if end := strings.Index(rest[2:], "*/"); end != -1 {
+			return COMMENT, offset + 2 + end + 2, false
+		}
+		return COMMENT, len(input), true
+	case r == '\'':
+		end, unterminated := quoted(input, offset, '\'', true)
+		return STRING, end, unterminated
+	case len(rest) > 1 && rest[1] == '\'' && strings.IndexByte("eErRxX", rest[0]) != -1:
+		// raw and hex strings have no escapes
+		escapes := rest[0] == 'e' || rest[0] == 'E'
+		end, unterminated := quoted(input, offset+1, '\'', escapes)
+		return STRING, end, unterminated
+	case r == '"':
+		end, unterminated := quoted(input, offset, '"', false)
+		return IDENTIFIER, end, unterminated
+	case r == '\\':
+		if nl := strings.IndexByte(rest, '\n'); nl != -1 {
+			return META, offset + nl, false
+		}
+		return META, len(input), false
+	case r == '{':
+		return body(input, offset)
+	case r >= '0' && r <= '9' || (r == '.' && len(rest) > 1 && rest[1] >= '0' && rest[1] <= '9'):
+		return NUMBER, number(input, offset), false
+	case r == '_' || unicode.IsLetter(r):
+		end := offset + size
+		for end < len(input) {
+			r, size := utf8.DecodeRuneInString(input[end:])
+			if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+				break
+			}
+			end += size
+		}
+		return WORD, end, false
+	}
+
+	for _, operator := range operators {
+		if strings.HasPrefix(rest, operator) {
+			return OPERATOR, offset + len(operator), false
+		}
+	}
+	return OPERATOR, offset + size, false
+}
+
+// A doubled quote is an escaped quote, as is a backslash followed by anything
+// (when escapes are on)
+func quoted(input string, offset int, quote byte, escapes bool) (int, bool) {
+	for i := offset + 1; i < len(input); i++ {
+		switch input[i] {
+		case '\\':
+			if escapes {
+				i += 1
+			}
+		case quote:
+			if i+1 < len(input) && input[i+1] == quote {
+				i += 1
+				continue
+			}
+			return i + 1, false
+		}
+	}
+	return len(input), true
+}
+
+// Bodies can have nested braces, but are otherwise opaque
+func body(input string, offset int) (int, int, bool) {
+	depth := 0
+	for i := offset; i < len(input); i++ {
+		switch input[i] {
+		case '{':
+			depth += 1
+		case '}':
+			depth -= 1
+			if depth == 0 {
+				return BODY, i + 1, false
+			}
+		}
+	}
+	return BODY, len(input), true
+}
+
+func number(input string, offset int) int {
+	end := offset
+	for end < len(input) && (isDigit(input[end]) || input[end] == '.') {
+		end += 1
+	}
+	// exponent
+	if end < len(input) && (input[end] == 'e' || input[end] == 'E') {
+		exponent := end + 1
+		if exponent < len(input) && (input[exponent] == '+' || input[exponent] == '-') {
+			exponent += 1
+		}
+		if exponent < len(input) && isDigit(input[exponent]) {
+			end = exponent
+			for end < len(input) && isDigit(input[end]) {
+				end += 1
+			}
+		}
+	}
+	return end
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
@@ -0,0 +1,346 @@
+package lexer
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Statement struct {
+	// Including its terminating ;, without the spaces around it (empty for a
+	// command on its own)
+	SQL string
+
+	// A \ command, either on its own or the \g which ended the SQL
+	Command string
+
+	// Where the statement (or command) starts, counting from 1
+	Line int
+
+	// The lines which follow a COPY ... FROM STDIN statement (each ending with
+	// a newline), sent to the server along with it
+	Data string
+}
+
+// Splits input into statements (and \ commands). Input can be added a bit at a
+// time (e.g. a line at a time, as it's typed), a statement is complete once
+// its ; has been added.
+//
+// A ; doesn't end a statement when it's in a string, a quoted identifier, a
+// comment or in the BEGIN ... END body of a function, procedure or trigger.
+// \g ends a statement (like a ;), any other \ command is returned on its own
+// and removed from the statement it's in.
+//
+// The lines after a COPY ... FROM STDIN statement are its data, not SQL. The
+// statement is complete once its data is: after its number of RECORDS (one per
+// line) or, when it doesn't say, at a line with only \. on it.
+type Splitter struct {
+	// the statement being added to, or the partial line of data of copy
+	pending string
+
+	// of the start of pending, counting from 0
+	line int
+
+	// a COPY ... FROM STDIN statement waiting for (the rest of) its data
+	copy *Statement
+
+	// the number of lines of data copy still needs (-1 until the \. line)
+	records int
+}
+
+func (s *Splitter) Add(text string) []Statement {
+	if s.copy != nil {
+		return s.addData(text)
+	}
+
+	var statements []Statement
+	var sql strings.Builder
+	b := &block{}
+
+	// line of the current token, of the start of the current statement and of
+	// what will be pending
+	line, start, pendingLine := s.line, -1, s.line
+
+	input := s.pending + text
+	tokens := Tokenize(input)
+	for i, token := range tokens {
+		tokenLine := line
+		line += strings.Count(token.Text, "\n")
+
+		switch token.Kind {
+		case META:
+			if IsGo(token.Text) {
+				if b.significant {
+					statements = append(statements, Statement{SQL: terminate(sql.String()), Command: token.Text, Line: start + 1})
+				}
+				sql.Reset()
+				b, start, pendingLine = &block{}, -1, line
+			} else {
+				statements = append(statements, Statement{Command: token.Text, Line: tokenLine + 1})
+			}
+			continue
+		case OPERATOR:
+			if token.Text == ";" && b.depth == 0 {
+				sql.WriteString(";")
+				if b.significant {
+					stmt := Statement{SQL: strings.TrimSpace(sql.String()), Line: start + 1}
+					if records, ok := copyFromStdin(stmt.SQL); ok {
+						// the data starts on the line after the ; (the rest of
+						// its line is ignored)
+						rest := input[token.Offset+1:]
+						s.copy, s.records, s.pending, s.line = &stmt, records, "", line+1
+						if nl := strings.IndexByte(rest, '\n'); nl != -1 {
+							return append(statements, s.addData(rest[nl+1:])...)
+						}
+						return statements
+					}
+					statements = append(statements, stmt)
+				}
+				sql.Reset()
+				b, start, pendingLine = &block{}, -1, line
+				continue
+			}
+		}
+
+		if start == -1 && !token.Insignificant() {
+			start = tokenLine
+		}
+		b.add(tokens, i)
+		sql.WriteString(token.Text)
+	}
+
+	s.pending = sql.String()
+	s.line = pendingLine
+	return statements
+}
+
+// Adds data to the pending COPY ... FROM STDIN statement, and whatever comes
+// after its data as more statements
+func (s *Splitter) addData(text string) []Statement {
+	for s.records != 0 {
+		nl := strings.IndexByte(text, '\n')
+		if nl == -1 {
+			// wait for the rest of the line
+			s.pending += text
+			return nil
+		}
+		line := s.pending + text[:nl+1]
+		s.pending, text = "", text[nl+1:]
+		s.line += 1
+		if strings.TrimRight(line, "\r\n") == "\\." {
+			break
+		}
+		s.copy.Data += line
+		if s.records > 0 {
+			s.records -= 1
+		}
+	}
+
+	stmt := *s.copy
+	s.copy = nil
+	return append([]Statement{stmt}, s.Add(text)...)
+}
+
+// The statement being added to (empty while the data of a COPY ... FROM STDIN
+// is being added)
+func (s *Splitter) Pending() string {
+	if s.copy != nil {
+		return ""
+	}
+	return s.pending
+}
+
+// Whether the data of a COPY ... FROM STDIN statement is being added
+func (s *Splitter) Copying() bool {
+	return s.copy != nil
+}
+
+// The number of lines of data the COPY ... FROM STDIN statement still needs,
+// -1 when its data ends at a line with only \. on it
+func (s *Splitter) Records() int {
+	return s.records
+}
+
+// Whether there's a statement being added to, spaces and comments don't count
+// (unless they're unterminated, like /* on its own)
+func (s *Splitter) Blank() bool {
+	if s.copy != nil {
+		return false
+	}
+	for _, token := range Tokenize(s.pending) {
+		if !token.Insignificant() || token.Unterminated {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Splitter) Reset() {
+	s.pending = ""
+	s.line = 0
+	s.copy = nil
+	s.records = 0
+}
+
+// \g [TARGET]
+func IsGo(command string) bool {
+	command = strings.TrimRight(command, "\n")
+	return command == "\\g" || strings.HasPrefix(command, "\\g ")
+}
+
+// Adds the ; to a statement which ended without one, on a line of its own if
+// the statement ends with a -- comment
+func terminate(sql string) string {
+	sql = strings.TrimSpace(sql)
+	tokens := Tokenize(sql)
+	if last := tokens[len(tokens)-1]; last.Kind == COMMENT && strings.HasPrefix(last.Text, "--") {
+		return sql + "\n;"
+	}
+	return sql + ";"
+}
+
+// Splits all of the input, the last statement doesn't need a ;
+func Split(input string) []Statement {
+	s := &Splitter{}
+	statements := s.Add(input)
+	if s.copy != nil {
+		// the input ended before the data did, the server will say if it's
+		// missing any
+		stmt := *s.copy
+		if s.pending != "" {
+			stmt.Data += s.pending + "\n"
+		}
+		return append(statements, stmt)
+	}
+	if s.Blank() {
+		return statements
+	}
+
+	// the pending statement starts at its first token which isn't a space or a
+	// comment
+	line := s.line
+	for _, token := range Tokenize(s.pending) {
+		if !token.Insignificant() {
+			break
+		}
+		line += strings.Count(token.Text, "\n")
+	}
+	return append(statements, Statement{SQL: terminate(s.pending), Line: line + 1})
+}
+
+// COPY [n [OFFSET m] RECORDS] INTO table ... FROM STDIN ..., returns n (or -1
+// when there's no number of records)
+func copyFromStdin(sql string) (int, bool) {
+	var tokens []Token
+	for _, token := range Tokenize(sql) {
+		if !token.Insignificant() {
+			tokens = append(tokens, token)
+		}
+	}
+	if len(tokens) < 5 || !tokens[0].Is("copy") {
+		return 0, false
+	}
+
+	records, i := -1, 1
+	if tokens[i].Kind == NUMBER {
+		n, err := strconv.Atoi(tokens[i].Text)
+		if err != nil {
+			return 0, false
+		}
+		records, i = n, i+1
+	}
+	// [OFFSET m] [RECORDS]
+	for i < len(tokens) && !tokens[i].Is("into") {
+		if !tokens[i].Is("offset") && !tokens[i].Is("records") && tokens[i].Kind != NUMBER {
+			return 0, false
+		}
+		i += 1
+	}
+
+	for ; i+1 < len(tokens); i++ {
+		if tokens[i].Is("from") && tokens[i+1].Is("stdin") {
+			return records, true
+		}
+	}
+	return 0, false
+}
+
+// Tracks the BEGIN ... END blocks of the statement's body
+type block struct {
+	// whether the statement has anything other than spaces and comments
+	significant bool
+
+	// the statement's significant words, until we know whether it has a body
+	words []string
+
+	// the statement creates a function, procedure or trigger
+	body bool
+
+	depth int
+
+	// the last word was END and the next one is part of it (as in END IF)
+	end bool
+}
+
+func (b *block) add(tokens []Token, i int) {
+	token := tokens[i]
+	if token.Insignificant() {
+		return
+	}
+	b.significant = true
+	if token.Kind != WORD {
+		return
+	}
+
+	if !b.body {
+		// CREATE [OR REPLACE] [FILTER|WINDOW] FUNCTION|PROCEDURE|TRIGGER|...
+		if len(b.words) < 5 {
+			b.words = append(b.words, strings.ToLower(token.Text))
+			if b.words[0] == "create" {
+				switch b.words[len(b.words)-1] {
+				case "function", "procedure", "trigger", "aggregate", "loader":
+					b.body = true
+				}
+			}
+		}
+		return
+	}
+
+	if b.end {
+		// the IF of END IF (or the CASE of END CASE) doesn't start a block
+		b.end = false
+		return
+	}
+
+	switch {
+	case token.Is("begin"), token.Is("case"):
+		b.depth += 1
+	case token.Is("end"):
+		switch nextWord(tokens, i) {
+		case "if", "while", "loop", "for", "repeat":
+			// these blocks didn't start with a BEGIN (or a CASE)
+			b.end = true
+		case "case":
+			b.end = true
+			b.depth -= 1
+		default:
+			b.depth -= 1
+		}
+		if b.depth < 0 {
+			b.depth = 0
+		}
+	}
+}
+
+// The next significant token, if it's a word
+func nextWord(tokens []Token, i int) string {
+	for _, token := range tokens[i+1:] {
+		if token.Insignificant() {
+			continue
+		}
+		if token.Kind == WORD {
+			return strings.ToLower(token.Text)
+		}
+		return ""
+	}
+	return ""
+}
@@ -0,0 +1,154 @@
+package lexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_Split(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  []Statement
+	}{
+		{"statements", "select 1; select 2;", []Statement{
+			{SQL: "select 1;", Line: 1},
+			{SQL: "select 2;", Line: 1},
+		}},
+		{"last statement without ;", "select 1;\nselect 2", []Statement{
+			{SQL: "select 1;", Line: 1},
+			{SQL: "select 2;", Line: 2},
+		}},
+		{"empty statements", ";; ;", nil},
+
+		// strings and identifiers
+		{"string", "select 'a;b';", []Statement{{SQL: "select 'a;b';", Line: 1}}},
+		{"doubled quote", "select 'it''s; fine';", []Statement{{SQL: "select 'it''s; fine';", Line: 1}}},
+		{"backslash escape", `select 'back\'slash;';`, []Statement{{SQL: `select 'back\'slash;';`, Line: 1}}},
+		{"E string", `select E'e\';x';`, []Statement{{SQL: `select E'e\';x';`, Line: 1}}},
+		{"R string", `select R'C:\dir\'; select 2;`, []Statement{
+			{SQL: `select R'C:\dir\';`, Line: 1},
+			{SQL: "select 2;", Line: 1},
+		}},
+		{"X string", "select X'AB;CD';", []Statement{{SQL: "select X'AB;CD';", Line: 1}}},
+		{"quoted identifier", `select "a""b;c" from t;`, []Statement{{SQL: `select "a""b;c" from t;`, Line: 1}}},
+		{"unterminated string", "select 'still; going", []Statement{{SQL: "select 'still; going;", Line: 1}}},
+
+		// comments
+		{"line comment", "select 1 -- a; b\n;", []Statement{{SQL: "select 1 -- a; b\n;", Line: 1}}},
+		{"block comment", "select /* a ; b */ 1;", []Statement{{SQL: "select /* a ; b */ 1;", Line: 1}}},
+		{"only comments", "-- a\n/* b; */\n", nil},
+		{"line of the first token", "-- a\n\n  select 1;", []Statement{{SQL: "-- a\n\n  select 1;", Line: 3}}},
+
+		// bodies
+		{"begin end", "create function f() returns int begin declare x int; set x = 1; return x; end; select 2;", []Statement{
+			{SQL: "create function f() returns int begin declare x int; set x = 1; return x; end;", Line: 1},
+			{SQL: "select 2;", Line: 1},
+		}},
+		{"if", "CREATE OR REPLACE FUNCTION f(i int) RETURNS int BEGIN IF i > 0 THEN RETURN 1; ELSE RETURN 0; END IF; END; select 2;", []Statement{
+			{SQL: "CREATE OR REPLACE FUNCTION f(i int) RETURNS int BEGIN IF i > 0 THEN RETURN 1; ELSE RETURN 0; END IF; END;", Line: 1},
+			{SQL: "select 2;", Line: 1},
+		}},
+		{"while and case statement", "create procedure p() begin while x do set x = 1; end while; case when y then set x = 2; end case; end; select 2;", []Statement{
+			{SQL: "create procedure p() begin while x do set x = 1; end while; case when y then set x = 2; end case; end;", Line: 1},
+			{SQL: "select 2;", Line: 1},
+		}},
+		{"case expression", "create function f() returns int begin return case when 1 = 1 then 1 else 2 end; end; select 2;", []Statement{
+			{SQL: "create function f() returns int begin return case when 1 = 1 then 1 else 2 end; end;", Line: 1},
+			{SQL: "select 2;", Line: 1},
+		}},
+		{"not a body", "begin transaction; select case when 1 = 1 then 2 end; commit;", []Statement{
+			{SQL: "begin transaction;", Line: 1},
+			{SQL: "select case when 1 = 1 then 2 end;", Line: 1},
+			{SQL: "commit;", Line: 1},
+		}},
+		{"braces", "create function py(i int) returns int language python { x = 1; return i * 2 }; select 2;", []Statement{
+			{SQL: "create function py(i int) returns int language python { x = 1; return i * 2 };", Line: 1},
+			{SQL: "select 2;", Line: 1},
+		}},
+
+		// commands
+		{"command", "select 1;\n\\x on\nselect 2", []Statement{
+			{SQL: "select 1;", Line: 1},
+			{Command: "\\x on", Line: 2},
+			{SQL: "select 2;", Line: 3},
+		}},
+		{"\\g", "select 1 \\g out.txt\nselect 2;", []Statement{
+			{SQL: "select 1;", Command: "\\g out.txt", Line: 1},
+			{SQL: "select 2;", Line: 2},
+		}},
+		{"\\g after --", "select 1 -- trailing\n\\g", []Statement{
+			{SQL: "select 1 -- trailing\n;", Command: "\\g", Line: 1},
+		}},
+
+		// copy data
+		{"copy records", "COPY 2 RECORDS INTO t FROM STDIN;\n1\t'a;\n2\t\"b\n\\x\nselect 1;", []Statement{
+			{SQL: "COPY 2 RECORDS INTO t FROM STDIN;", Line: 1, Data: "1\t'a;\n2\t\"b\n"},
+			{Command: "\\x", Line: 4},
+			{SQL: "select 1;", Line: 5},
+		}},
+		{"copy until \\.", "copy offset 2 into t from stdin using delimiters ',';\na;b\n\\.\nselect 1;", []Statement{
+			{SQL: "copy offset 2 into t from stdin using delimiters ',';", Line: 1, Data: "a;b\n"},
+			{SQL: "select 1;", Line: 4},
+		}},
+		{"copy without the end of its data", "copy into t from stdin;\na\nb", []Statement{
+			{SQL: "copy into t from stdin;", Line: 1, Data: "a\nb\n"},
+		}},
+		{"copy from a file", "copy into t from 'x;y.csv'; select 1;", []Statement{
+			{SQL: "copy into t from 'x;y.csv';", Line: 1},
+			{SQL: "select 1;", Line: 1},
+		}},
+	}
+
+	for _, c := range cases {
+		if got := Split(c.input); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s:\n  got:  %#v\n  want: %#v", c.name, got, c.want)
+		}
+	}
+}
+
+// As typed at the prompt, a line at a time
+func Test_Splitter_Add(t *testing.T) {
+	s := &Splitter{}
+	lines := []struct {
+		text  string
+		count int
+		blank bool
+	}{
+		{"create function f()\n", 0, false},
+		{"returns int begin\n", 0, false},
+		{"return 1;\n", 0, false},
+		{"end;\n", 1, true},
+		{"/* open\n", 0, false},
+		{"comment */ select 1;\n", 1, true},
+		{"select 'a\n", 0, false},
+		{"\\x;' from t;\n", 1, true},
+		{"copy 1 records into t from stdin;\n", 0, false},
+		{"a;b\n", 1, true},
+		{"copy into t from stdin;\n", 0, false},
+		{"x\n", 0, false},
+		{"\\.\n", 1, true},
+	}
+	for _, line := range lines {
+		statements := s.Add(line.text)
+		if len(statements) != line.count || s.Blank() != line.blank {
+			t.Errorf("%q: got %d statements (blank %v), want %d (blank %v)", line.text, len(statements), s.Blank(), line.count, line.blank)
+		}
+	}
+}
+
+// Whatever was being added is dropped, so what's added next starts afresh
+func Test_Splitter_Reset(t *testing.T) {
+	s := &Splitter{}
+	for _, text := range []string{"select\n", "copy 3 records into t from stdin;\n1\n"} {
+		s.Add(text)
+		s.Reset()
+		if !s.Blank() || s.Copying() || s.Records() != 0 {
+			t.Errorf("%q: expected a blank splitter after a reset (copying %v, %d records)", text, s.Copying(), s.Records())
+		}
+		want := []Statement{{SQL: "select 1;", Line: 1}}
+		if got := s.Add("select 1;\n"); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q:\n  got:  %#v\n  want: %#v", text, got, want)
+		}
+	}
+}
@@ -1,18 +1,18 @@
 package main
 
 import (
-	"bytes"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/jessevdk/go-flags"
 	"github.com/karlseguin/msql/commands"
 	"github.com/karlseguin/msql/driver"
+	"github.com/karlseguin/msql/lexer"
 	"github.com/karlseguin/msql/outputs"
 
 	"github.com/knz/go-libedit"
@@ -35,16 +35,25 @@ func init() {
 	cmds["\\?"] = commands.Help{}
 	cmds["\\f"] = commands.Format{}
 	cmds["\\x"] = commands.Expanded{}
+	cmds["\\pset"] = commands.Pset{}
 	cmds["\\d"] = commands.Describe{}
 	cmds["\\d+"] = commands.Describe{}
 	cmds["\\du"] = commands.Users{}
 	cmds["\\timing"] = commands.Timing{}
+	cmds["\\autocommit"] = commands.AutoCommit{}
+	cmds["\\prepare"] = commands.Prepare{}
+	cmds["\\exec"] = commands.Exec{}
+	cmds["\\deallocate"] = commands.Deallocate{}
+	cmds["\\o"] = commands.Output{}
+	cmds["\\tee"] = commands.Tee{}
+	cmds["\\g"] = commands.Go{}
 }
 
 func main() {
 	var opts struct {
 		Port        uint32       `description:"port to connect to" short:"p" long:"port" default:"50000"`
-		Host        string       `description:"host to connect to" short:"h" long:"host" default:"127.0.0.1"`
+		Host        string       `description:"host to connect to (a path connects to a unix domain socket)" short:"h" long:"host" default:"127.0.0.1"`
+		Socket      string       `description:"unix domain socket, or the directory containing .s.monetdb.PORT, to connect to" long:"socket"`
 		Database    string       `description:"database to connect to" short:"d" long:"database" default:"monetdb"`
 		UserName    string       `description:"username to connect as" short:"u" long:"username" default:"monetdb"`
 		Verbose     bool         `description:"verbose logging" long:"verbose"`
@@ -52,11 +61,20 @@ func main() {
 		Schema      string       `description:"schema to use when connecting" short:"s" long:"schema"`
 		Role        string       `description:"role to use when connecting" short:"r" long:"role"`
 		Command     string       `description:"executes the command and exists" short:"c"`
-		Format      string       `description:"default output format (sql|raw|expanded)" default:"sql"`
+		Format      string       `description:"default output format, followed by options (e.g. \"csv delimiter=; null=NULL header=off\")" long:"format" default:"sql"`
 		ExitOnError bool         `description:"exit on error" long:"exit-on-error"`
 		Help        func() error `description:"show this help screen" long:"help"`
 		File        string       `description:"file to exist" long:"file" short:"f"`
 		Version     bool         `description:"print the version number" long:"version"`
+		TransferDir string       `description:"directory COPY ... ON CLIENT can read and write files in (default: off, . for the current directory)" long:"transfer-dir"`
+
+		TLS           bool   `description:"connect using TLS" long:"tls"`
+		TLSCA         string `description:"TLS: PEM file of the CA to verify the server with (default: system CAs)" long:"tls-ca"`
+		TLSCert       string `description:"TLS: PEM file of the client certificate" long:"tls-cert"`
+		TLSKey        string `description:"TLS: PEM file of the client key" long:"tls-key"`
+		TLSServerName string `description:"TLS: name to verify the server certificate against (default: host)" long:"tls-server-name"`
+		TLSCertHash   string `description:"TLS: pin the server certificate (sha256:HEX) instead of verifying it" long:"tls-cert-hash"`
+		TLSInsecure   bool   `description:"TLS: don't verify the server certificate" long:"tls-insecure"`
 	}
 
 	parser := flags.NewParser(&opts, flags.Default & ^flags.HelpFlag)
@@ -65,7 +83,12 @@ func main() {
 		os.Exit(1)
 		return nil
 	}
-	_, err := parser.Parse()
+	// the formats come from the outputs registry, which might have more than
+	// the built-in formats
+	format := parser.FindOptionByLongName("format")
+	format.Description = strings.Replace(format.Description, "default output format", "default output format ("+strings.Join(outputs.Names(), "|")+")", 1)
+	parser.Usage = "[OPTIONS] [URL]\n\nURL: monetdb://user@host:port/database?schema=SCHEMA&role=ROLE (or $MONETDB_URL)"
+	args, err := parser.Parse()
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -89,11 +112,50 @@ func main() {
 		Host:     fmt.Sprintf("%s:%d", opts.Host, opts.Port),
 		UserName: opts.UserName,
 		Database: opts.Database,
-		Password: getPassword(preferences, fmt.Sprintf("%s:%d:%s:%s:", opts.Host, opts.Port, opts.Database, opts.UserName)),
 		Schema:   opts.Schema,
 		Role:     opts.Role,
 	}
 
+	if opts.Socket != "" {
+		config.Socket = unixSocket(opts.Socket, opts.Port)
+	} else if strings.HasPrefix(opts.Host, "/") {
+		config.Socket = unixSocket(opts.Host, opts.Port)
+	}
+
+	if opts.TLS || opts.TLSCA != "" || opts.TLSCert != "" || opts.TLSKey != "" || opts.TLSServerName != "" || opts.TLSCertHash != "" || opts.TLSInsecure {
+		config.TLS = &driver.TLSConfig{
+			CAFile:             opts.TLSCA,
+			CertFile:           opts.TLSCert,
+			KeyFile:            opts.TLSKey,
+			ServerName:         opts.TLSServerName,
+			CertHash:           opts.TLSCertHash,
+			InsecureSkipVerify: opts.TLSInsecure,
+		}
+	}
+
+	// a URL takes priority over the individual flags
+	url := os.Getenv("MONETDB_URL")
+	if len(args) > 0 {
+		url = args[0]
+	}
+	if url != "" {
+		if err := config.ApplyURL(url); err != nil {
+			log.WithFields(log.Fields{"context": "connection url"}).Fatal(err)
+		}
+	}
+
+	transferDir := preferences.transferDir
+	if opts.TransferDir != "" {
+		transferDir = opts.TransferDir
+	}
+	if transferDir != "" && transferDir != "off" {
+		config.FileTransfer = driver.DirectoryTransfer{Dir: transferDir}
+	}
+
+	if config.Password == "" {
+		config.Password = getPassword(preferences, fmt.Sprintf("%s:%s:%s:", config.Host, config.Database, config.UserName))
+	}
+
 	conn, err := driver.Open(config)
 	if err != nil {
 		log.WithFields(log.Fields{
@@ -102,15 +164,20 @@ func main() {
 		}).Fatal(err)
 	}
 
-	context := NewContext(conn, os.Stdout)
+	context := NewContext(config, conn, os.Stdout)
 	defer context.Close()
 
 	context.Timing(preferences.timing)
-	context.Format(strings.ToLower(opts.Format))
+	if _, err := commands.ApplyFormat(context, opts.Format); err != nil {
+		log.WithFields(log.Fields{"context": "format"}).Fatal(err)
+	}
 	context.exitOnError = opts.ExitOnError
+	context.autoReconnect = preferences.autoReconnect
+	context.display = preferences.display
 
 	// handles -c or -f argument or stdin input
 	conditionallyExecuteAndExit(opts.Command, opts.File, context)
+	context.interactive = true
 
 	context.WriteString(fmt.Sprintf("client version: %s\n", VERSION))
 	context.WriteString(fmt.Sprintf("server version: %s (release: %s)\n", context.version, context.release))
@@ -124,6 +191,9 @@ func main() {
 
 	defer prompt.Close()
 	prompt.RebindControlKeys()
+	context.editor = prompt
+	context.catalog = newCatalog(context.config)
+	prompt.SetCompleter(&completer{prompt: prompt, context: context})
 	if err := prompt.UseHistory(500, true); err != nil {
 		log.WithFields(log.Fields{"context": "libedit use history"}).Error(err)
 	} else if preferences.historyFile != "" {
@@ -139,6 +209,13 @@ func main() {
 		line, err := prompt.GetLine()
 		if err != nil {
 			if err == libedit.ErrInterrupted {
+				// Ctrl-C only discards whatever was typed
+				context.WriteString("\n")
+				continue
+			}
+			if err == io.EOF {
+				// Ctrl-D
+				context.WriteString("\n")
 				return
 			}
 			log.WithFields(log.Fields{"context": "GetLine"}).Fatal(err)
@@ -164,7 +241,13 @@ func command(prompt libedit.EditLine, context *Context, line string) {
 	if len(line) == 0 {
 		return
 	}
+	runCommand(context, line)
+	prompt.AddHistory(line)
+	prompt.SaveHistory()
+}
 
+// Also used by scripts
+func runCommand(context *Context, line string) {
 	args := ""
 	cmd := line
 	parts := strings.SplitN(line, " ", 2)
@@ -179,8 +262,6 @@ func command(prompt libedit.EditLine, context *Context, line string) {
 	} else {
 		log.Error("invalid command, type \\h for a list of commands")
 	}
-	prompt.AddHistory(line)
-	prompt.SaveHistory()
 }
 
 // Statements are passed to the monetdb server for execution. Statements are
@@ -188,145 +269,180 @@ func command(prompt libedit.EditLine, context *Context, line string) {
 // essentially called when a non-command line is entered in the main loop. Once
 // here, this function has its own readline loop to get the full statement.
 func statement(prompt libedit.EditLine, context *Context, line string) {
-	state := &state{context: context}
+	splitter := &lexer.Splitter{}
+	copying := false
 	for {
-		complete, rest := state.add(prompt, line)
-		if complete {
-			// we have a full statement, execute it
-			sql := state.String()
-			prompt.AddHistory(sql)
+		for i, stmt := range splitter.Add(line) {
+			if stmt.SQL == "" {
+				// a command in the middle of a statement, which we'll allow (like psql)
+				command(prompt, context, stmt.Command)
+				continue
+			}
+
+			if stmt.Command != "" {
+				prompt.AddHistory(strings.TrimSuffix(stmt.SQL, ";") + " " + stmt.Command)
+			} else {
+				prompt.AddHistory(stmt.SQL)
+			}
 			prompt.SaveHistory()
-			query(context, sql)
-			if rest != "" {
-				// not great, but it works
+
+			if i > 0 {
+				// more than one statement on a line, show which one this is
 				context.Prompt()
-				context.Write([]byte(rest))
-				if rest[len(rest)-1] != '\n' {
-					context.Write([]byte("\n"))
-				}
-				statement(prompt, context, rest)
-				return
+				context.WriteString(stmt.SQL + "\n")
 			}
+			runStatement(context, stmt)
+		}
+		if splitter.Blank() {
 			return
 		}
+		if splitter.Copying() && !copying && splitter.Records() == -1 {
+			context.WriteString("Enter the data, ending with a line containing only \\.\n")
+		}
+		copying = splitter.Copying()
+
 		prompt.SetLeftPrompt("")
-		line, _ = prompt.GetLine()
+		var err error
+		context.pending = splitter.Pending()
+		line, err = prompt.GetLine()
+		context.pending = ""
+		if err != nil {
+			// Ctrl-C (or Ctrl-D) discards the statement we've collected so far
+			context.WriteString("\n")
+			return
+		}
+	}
+}
+
+// Runs a statement from the splitter, which can end with \g TARGET rather than
+// a semi-colon. Also used by scripts.
+func runStatement(context *Context, stmt lexer.Statement) {
+	target := ""
+	if stmt.Command != "" {
+		target = strings.TrimSpace(stmt.Command[2:])
+	}
+	// \g on its own re-runs the statement, without the data of a COPY
+	context.lastQuery = stmt.SQL
+	if err := context.queryTo(stmt.SQL, stmt.Data, target); err != nil {
+		log.WithFields(log.Fields{"context": "\\g"}).Error(err)
 	}
 }
 
 // The statement function has collected a full statement, send it to the server
-// and deal with the response
-func query(context *Context, statement string) {
-	if err := context.conn.Send("s", statement); err != nil {
-		handleDriverError(err) // can exit
-		if context.exitOnError {
-			os.Exit(1)
+// and deal with the response. data is the data of a COPY ... FROM STDIN
+// statement, which is sent along with it.
+func query(context *Context, statement string, data string) {
+	parts := []string{"s", statement}
+	if data != "" {
+		parts = append(parts, "\n", data)
+	}
+	send := func() error {
+		return context.conn.Send(parts...)
+	}
+	run(context, statement, send, func() { query(context, statement, data) })
+}
+
+// Sends a request (using send) and renders its reply. statement is what the
+// request does, as sql. When the connection drops, rerun (if not nil) is
+// offered to run it again on the new connection.
+func run(context *Context, statement string, send func() error, rerun func()) {
+	// Output goes where \o, \tee or \g send it and through the pager (if
+	// there's one), including the footer. It's released as soon as the results
+	// have been rendered, since what follows (like reconnecting) might need the
+	// terminal.
+	out := context.out
+	context.out = context.output()
+	pager := context.newPager()
+	release := func() {
+		if pager != nil {
+			pager.Close()
+			pager = nil
 		}
-		return
+		context.out = out
+	}
+	defer release()
+	if pager != nil {
+		context.out = pager
 	}
 
-	var err error
-	var meta *driver.Meta
-	start := time.Now()
-	if context.format == FORMAT_RAW {
-		meta, err = outputs.Raw(context.conn, context.out)
-	} else if context.format == FORMAT_EXPANDED {
-		meta, err = outputs.Expanded(context.conn, context.out)
-	} else if context.format == FORMAT_TRASH {
-		meta, err = outputs.Trash(context.conn)
-	} else {
-		meta, err = outputs.SQL(context.conn, context.out)
+	cancel := watchInterrupt(context)
+	defer cancel.Stop()
+	if pager != nil {
+		// quitting the pager early stops the query
+		pager.onQuit = cancel.Cancel
 	}
 
-	if err != nil {
-		handleDriverError(err)
+	if err := send(); err != nil {
+		// what follows (the reconnect messages and prompt) goes to the terminal
+		release()
+		if context.handleDisconnect(err, false, rerun) {
+			return
+		}
+		handleDriverError(err) // can exit
 		if context.exitOnError {
 			os.Exit(1)
 		}
+		return
 	}
-	duration := time.Since(start)
 
-	// output the meta data
-	if meta != nil {
-		if meta.RowCount == 1 {
-			context.WriteString("(1 row)\n")
-		} else {
-			context.WriteString(fmt.Sprintf("(%d rows)\n", meta.RowCount))
+	// called after each result of the reply
+	start := time.Now()
+	footer := func(meta *driver.Meta) {
+		if cancel.Cancelled() {
+			return
 		}
-		context.WriteString(fmt.Sprintf("\nsql:%0.3f opt:%0.3f run:%0.3f clk:%s\n", float32(meta.SqlTime)/1000, float32(meta.OptTime)/1000, float32(meta.RunTime)/1000, duration))
-
-	} else if context.timing {
-		context.WriteString(fmt.Sprintf("\nclk:%s\n", duration))
+		duration := time.Since(start)
+		if meta != nil && context.display.ShowFooter {
+			context.WriteString(context.display.RenderFooter(meta, duration))
+		} else if context.timing {
+			context.WriteString(fmt.Sprintf("\nclk:%s\n", duration))
+		}
+		start = time.Now()
 	}
-}
-
-// Tracks the state of our statement parsing
-type state struct {
-	context *Context
-
-	// Accumlats the stament (one line at a time)
-	bytes.Buffer
-
-	// The literal delimiter, which will be either ' or " (or 0 if we're not
-	// in a literal). This is the character we're looking for to end the literal
-	literal byte
 
-	// Whether the last character was an escape character or not. This tells us
-	// to ignor the next character.
-	escape bool
-}
+	if q, ok := context.formatter.(outputs.QueryFormatter); ok {
+		q.SetQuery(statement)
+	}
+	if d, ok := context.formatter.(outputs.DisplayFormatter); ok {
+		// the terminal could have been resized since the last query
+		context.display.Width = terminalWidth(context.out)
+		d.SetDisplay(context.display)
+	}
+	err := outputs.Render(context.conn, cancel, footer, context.format, context.formatter)
+	release()
 
-// We have a line from the user. We need to figure out whether there's a full
-// statement in here or not. A full statement is delimited by a semi-colon, but
-// that semi-colon can't be proceed by a \, and can't be inside a literal string
-// (either single or double quoted)
-func (s *state) add(prompt libedit.EditLine, line string) (bool, string) {
-	escape := s.escape
-	for i, c := range line {
-		if escape {
-			escape = false
-			s.escape = false
-			continue
-		}
-		if c == '\\' {
-			// if the first character is \
-			// and we aren't in a literal
-			// than this is a command embedded in the SQL, which we'll allow (like psql)
-			if i == 0 && s.literal == 0 {
-				command(prompt, s.context, strings.TrimRight(line, "\n"))
-				return false, ""
-			}
-			escape = true
-			s.escape = true
-			continue
+	if cancel.Cancelled() {
+		// the server's "query aborted" error is expected, anything else isn't
+		if driverErr, ok := err.(driver.Error); err != nil && !(ok && driverErr.Source == driver.MONETDB_ERROR) {
+			handleDriverError(err)
 		}
+		context.WriteString("query cancelled\n")
+		return
+	}
 
-		if c == ';' && s.literal == 0 {
-			// We have a full statement statement. Add the line up to and including
-			// (thus the +1) semi colon
-			s.WriteString(line[:i+1])
-			return true, strings.TrimSpace(line[i+1:])
+	if err != nil {
+		if context.handleDisconnect(err, true, rerun) {
+			return
 		}
-
-		if c == '"' || c == '\'' {
-			b := byte(c)
-			if b == s.literal {
-				s.literal = 0 // we're closing a matching pair
-			} else if s.literal == 0 {
-				s.literal = b //we're starting a new literal
-			}
-			// The else case is that this is a double quote inside a yet-unclosed
-			// single quote, or a single quote inside a yet-unclosed doule quote.
-			// Either way, it's just a value in a literal.
+		handleDriverError(err)
+		if context.exitOnError {
+			os.Exit(1)
+		}
+		if context.timing {
+			context.WriteString(fmt.Sprintf("\nclk:%s\n", time.Since(start)))
 		}
+		return
 	}
 
-	// We don't have a full statement, add the line to our buffer as-is and get
-	// more from the user
-	s.WriteString(line)
-	return false, ""
+	if changesSession(statement) {
+		context.refreshSession()
+	}
+	if context.catalog != nil && changesCatalog(statement) {
+		context.catalog.Refresh()
+	}
 }
 
+// Logs the error, exits when the connection is gone
 func handleDriverError(err error) {
 	if driverErr, ok := err.(driver.Error); ok && driverErr.Inner != nil {
 		err = driverErr.Inner
@@ -341,46 +457,11 @@ func handleDriverError(err error) {
 	log.Error(err)
 }
 
-func conditionallyExecuteAndExit(cArg string, fArg string, context *Context) {
-	var input string
-	if cArg != "" {
-		input = strings.TrimSpace(cArg)
-	} else if fArg != "" {
-		data, err := ioutil.ReadFile(fArg)
-		if err != nil {
-			log.WithFields(log.Fields{"context": "fArg read"}).Fatal(err)
-		}
-		input = string(data)
-	} else {
-		fi, err := os.Stdin.Stat()
-		if err != nil {
-			log.WithFields(log.Fields{"context": "stat stdin"}).Error(err)
-		} else if fi.Mode()&os.ModeNamedPipe != 0 {
-			data, err := ioutil.ReadAll(os.Stdin)
-			if err != nil {
-				log.WithFields(log.Fields{"context": "stdin read"}).Fatal(err)
-			}
-			input = string(data)
-		}
-	}
-
-	// no -c, -f or stdin
-	if input == "" {
-		return
-	}
-
-	queries := strings.Split(input, ";")
-	for i, q := range queries {
-		if q == "" {
-			continue
-		}
-		if !strings.HasSuffix(q, ";") {
-			q += ";"
-		}
-		if i > 0 {
-			context.WriteString("\n")
-		}
-		query(context, q)
+// The path can be the socket itself or the directory containing it, in which
+// case the socket is named (by monetdb) after the port.
+func unixSocket(path string, port uint32) string {
+	if fi, err := os.Stat(path); err == nil && fi.IsDir() {
+		return filepath.Join(path, fmt.Sprintf(".s.monetdb.%d", port))
 	}
-	os.Exit(0)
+	return path
 }
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Where query results go. By default that's stdout, but \o sends them to a
+// file or a command, \tee also copies them to a file and \g sends a single
+// query's results somewhere else. Messages from commands (like \f) always go
+// to stdout.
+func (c *Context) output() io.Writer {
+	if c.once != nil {
+		return c.once
+	}
+	out := c.stdout
+	if c.redirect != nil {
+		out = c.redirect
+	}
+	if c.tee != nil {
+		out = io.MultiWriter(out, c.tee)
+	}
+	return out
+}
+
+// \o TARGET, an empty target goes back to stdout
+func (c *Context) Output(target string) error {
+	var redirect io.WriteCloser
+	if target != "" {
+		var err error
+		if redirect, err = openOutput(target, c.stdout); err != nil {
+			return err
+		}
+	}
+	if c.redirect != nil {
+		c.redirect.Close()
+	}
+	c.redirect = redirect
+	return nil
+}
+
+// \tee FILE, an empty file stops copying. Like mysql's tee, the file is
+// appended to.
+func (c *Context) Tee(file string) error {
+	var tee io.WriteCloser
+	if file != "" {
+		f, err := os.OpenFile(expandHome(file), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		tee = f
+	}
+	if c.tee != nil {
+		c.tee.Close()
+	}
+	c.tee = tee
+	return nil
+}
+
+// \g TARGET, runs the query with its results going to TARGET (or to the
+// current output if TARGET is empty)
+func (c *Context) QueryTo(sql string, target string) error {
+	return c.queryTo(sql, "", target)
+}
+
+// Like QueryTo, for a statement with data (see query)
+func (c *Context) queryTo(sql string, data string, target string) error {
+	if target == "" {
+		query(c, sql, data)
+		return nil
+	}
+	once, err := openOutput(target, c.stdout)
+	if err != nil {
+		return err
+	}
+	c.once = once
+	query(c, sql, data)
+	c.once = nil
+	return once.Close()
+}
+
+func (c *Context) LastQuery() string {
+	return c.lastQuery
+}
+
+func (c *Context) closeOutputs() {
+	if c.redirect != nil {
+		c.redirect.Close()
+		c.redirect = nil
+	}
+	if c.tee != nil {
+		c.tee.Close()
+		c.tee = nil
+	}
+}
+
+// A target is a file or, when it starts with |, a shell command which gets the
+// output on its stdin (its own output goes to stdout)
+func openOutput(target string, stdout io.Writer) (io.WriteCloser, error) {
+	if !strings.HasPrefix(target, "|") {
+		return os.Create(expandHome(target))
+	}
+
+	command := strings.TrimSpace(target[1:])
+	if command == "" {
+		return nil, fmt.Errorf("missing command after |")
+	}
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	pipe, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return &pipeOutput{cmd: cmd, pipe: pipe}, nil
+}
+
+type pipeOutput struct {
+	cmd  *exec.Cmd
+	pipe io.WriteCloser
+}
+
+func (p *pipeOutput) Write(data []byte) (int, error) {
+	return p.pipe.Write(data)
+}
+
+// Waits for the command to finish, so that its output isn't mixed with ours
+func (p *pipeOutput) Close() error {
+	p.pipe.Close()
+	return p.cmd.Wait()
+}
+
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,83 @@
+package outputs
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+var asciidocEscaper = strings.NewReplacer("|", "\\|")
+
+type asciidocFormatter struct {
+	captioned
+	displayed
+}
+
+func (f *asciidocFormatter) Render(result driver.Result, out io.Writer) error {
+	return renderAsciiDoc(result, out, f.caption(), f.display)
+}
+
+func renderAsciiDoc(result driver.Result, out io.Writer, caption string, display Display) error {
+	result.SetNull(display.Null)
+	types := result.Types()
+
+	w := bufio.NewWriter(out)
+	if caption != "" {
+		// a block title has to fit on a single line
+		w.WriteString(".")
+		w.WriteString(strings.Join(strings.Fields(caption), " "))
+		w.WriteString("\n")
+	}
+
+	cols := make([]string, len(types))
+	for i, tpe := range types {
+		if isNumeric(tpe) {
+			cols[i] = ">"
+		} else {
+			cols[i] = "<"
+		}
+	}
+	w.WriteString(`[cols="`)
+	w.WriteString(strings.Join(cols, ","))
+	w.WriteString(`"`)
+	if display.ShowHeader {
+		w.WriteString(`,options="header"`)
+	}
+	w.WriteString("]\n|===\n")
+
+	writeRow := func(row []string) {
+		for i, value := range row {
+			if i > 0 {
+				w.WriteString(" ")
+			}
+			w.WriteString("|")
+			w.WriteString(asciidocEscaper.Replace(value))
+		}
+		w.WriteString("\n")
+	}
+
+	if display.ShowHeader {
+		labels := make([]string, len(types))
+		for i, column := range result.Columns() {
+			labels[i] = display.label(column, types[i])
+		}
+		writeRow(labels)
+		w.WriteString("\n")
+	}
+
+	err := eachPage(result, w, func(rows [][]string) {
+		for _, row := range rows {
+			for i, value := range row {
+				row[i] = display.number(types[i], value)
+			}
+			writeRow(row)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	io.WriteString(out, "|===\n")
+	return nil
+}
@@ -0,0 +1,148 @@
+package outputs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+// Also used for tsv, which is the same thing with a tab delimiter
+type CSVOptions struct {
+	Delimiter rune
+	Quote     rune
+	Null      string
+	Header    bool
+}
+
+func DefaultCSVOptions() CSVOptions {
+	return CSVOptions{Delimiter: ',', Quote: '"', Header: true}
+}
+
+func DefaultTSVOptions() CSVOptions {
+	return CSVOptions{Delimiter: '\t', Quote: '"', Header: true}
+}
+
+var csvOptions = []Option{
+	{"delimiter", "a single character (or tab) between values"},
+	{"quote", "a single character to quote values with"},
+	{"null", "the text to output for NULL (defaults to nothing)"},
+	{"header", "on|off, output the column names"},
+}
+
+// Meant for other programs, so most display settings (e.g. numericLocale and
+// types) don't apply. \pset null and header do, unless the format's own null
+// and header options are given.
+type csvFormatter struct {
+	displayed
+	options CSVOptions
+
+	// whether the null and header options were given
+	null   bool
+	header bool
+}
+
+func (f *csvFormatter) Render(result driver.Result, out io.Writer) error {
+	options := f.options
+	// NULL is nothing unless \pset null was changed from its default (which
+	// is meant for people rather than programs)
+	if !f.null && f.display.Null != DefaultDisplay().Null {
+		options.Null = f.display.Null
+	}
+	if !f.header {
+		options.Header = f.display.ShowHeader
+	}
+	return renderCSV(result, out, options)
+}
+
+func (f *csvFormatter) SetOption(name string, value string) error {
+	switch name {
+	case "delimiter":
+		r, err := singleRune(value)
+		if err != nil {
+			return err
+		}
+		f.options.Delimiter = r
+	case "quote":
+		r, err := singleRune(value)
+		if err != nil {
+			return err
+		}
+		f.options.Quote = r
+	case "null":
+		f.options.Null = value
+		f.null = true
+	case "header":
+		f.options.Header = isOn(value)
+		f.header = true
+	}
+	return nil
+}
+
+func renderCSV(result driver.Result, out io.Writer, options CSVOptions) error {
+	result.SetNull(null)
+
+	// a value needs to be quoted (RFC 4180) if it contains any of these
+	special := string([]rune{options.Delimiter, options.Quote, '\r', '\n'})
+	quote := string(options.Quote)
+	escapedQuote := quote + quote
+
+	w := bufio.NewWriter(out)
+	writeRow := func(row []string) {
+		for i, value := range row {
+			if i > 0 {
+				w.WriteRune(options.Delimiter)
+			}
+			if value == null {
+				w.WriteString(options.Null)
+				continue
+			}
+			// a value which reads like NULL (by default, the empty string) is
+			// quoted, so that the two can be told apart
+			if value == options.Null || strings.ContainsAny(value, special) {
+				w.WriteString(quote)
+				w.WriteString(strings.ReplaceAll(value, quote, escapedQuote))
+				w.WriteString(quote)
+			} else {
+				w.WriteString(value)
+			}
+		}
+		w.WriteString("\r\n")
+	}
+
+	if options.Header {
+		writeRow(result.Columns())
+	}
+
+	for {
+		rows, err := result.Next()
+		if err != nil {
+			w.Flush()
+			return err
+		}
+		if rows == nil {
+			return w.Flush()
+		}
+		for _, row := range rows {
+			writeRow(row)
+		}
+		// flush every page so that the output streams
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+}
+
+// \t and tab are accepted since a tab is awkward to type
+func singleRune(value string) (rune, error) {
+	if value == "\\t" || value == "tab" {
+		return '\t', nil
+	}
+	runes := []rune(value)
+	if len(runes) != 1 {
+		return 0, fmt.Errorf("expected a single character, got '%s'", value)
+	}
+	return runes[0], nil
+}
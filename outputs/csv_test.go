@@ -0,0 +1,80 @@
+package outputs
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_CSV(t *testing.T) {
+	result := func() *fakeResult {
+		return &fakeResult{
+			columns: []string{"id", "name"},
+			types:   []string{"int", "varchar"},
+			pages: [][][]string{
+				{{"1", "plain"}, {"2", "a,b"}, {"3", `say "hi"`}},
+				{{"4", "two\nlines"}, {"NULL", ""}, {"6", "-"}},
+			},
+		}
+	}
+
+	cases := []struct {
+		name    string
+		options CSVOptions
+		want    string
+	}{
+		{"csv", DefaultCSVOptions(), "id,name\r\n1,plain\r\n2,\"a,b\"\r\n3,\"say \"\"hi\"\"\"\r\n4,\"two\nlines\"\r\n,\"\"\r\n6,-\r\n"},
+		{"tsv", DefaultTSVOptions(), "id\tname\r\n1\tplain\r\n2\ta,b\r\n3\t\"say \"\"hi\"\"\"\r\n4\t\"two\nlines\"\r\n\t\"\"\r\n6\t-\r\n"},
+		{"null", CSVOptions{Delimiter: ';', Quote: '\'', Null: "-"}, "1;plain\r\n2;a,b\r\n3;say \"hi\"\r\n4;'two\nlines'\r\n-;\r\n6;'-'\r\n"},
+	}
+
+	for _, c := range cases {
+		var out strings.Builder
+		if err := renderCSV(result(), &out, c.options); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.String(); got != c.want {
+			t.Errorf("%s:\n  got:  %q\n  want: %q", c.name, got, c.want)
+		}
+	}
+}
+
+// \pset null and header, unless the format's own options are given
+func Test_CSV_Display(t *testing.T) {
+	result := func() *fakeResult {
+		return &fakeResult{
+			columns: []string{"id"},
+			types:   []string{"int"},
+			pages:   [][][]string{{{"1"}, {"NULL"}}},
+		}
+	}
+
+	display := DefaultDisplay()
+	changed := DefaultDisplay()
+	changed.Null, changed.ShowHeader = "(null)", false
+
+	cases := []struct {
+		name    string
+		display Display
+		options map[string]string
+		want    string
+	}{
+		{"defaults", display, nil, "id\r\n1\r\n\r\n"},
+		{"pset", changed, nil, "1\r\n(null)\r\n"},
+		{"options", changed, map[string]string{"null": "", "header": "on"}, "id\r\n1\r\n\r\n"},
+	}
+
+	for _, c := range cases {
+		f := &csvFormatter{options: DefaultCSVOptions(), displayed: newDisplayed()}
+		for name, value := range c.options {
+			f.SetOption(name, value)
+		}
+		f.SetDisplay(c.display)
+		var out strings.Builder
+		if err := f.Render(result(), &out); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.String(); got != c.want {
+			t.Errorf("%s:\n  got:  %q\n  want: %q", c.name, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package outputs
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+// What to do with values which don't fit in their column (because the table
+// has been narrowed to fit the terminal)
+const (
+	OVERFLOW_NONE     = iota // the table isn't narrowed
+	OVERFLOW_WRAP            // values are wrapped within their column
+	OVERFLOW_TRUNCATE        // values are cut, with an ellipsis
+)
+
+// When output goes through the pager
+const (
+	PAGER_ON     = iota // when it doesn't fit on the screen
+	PAGER_OFF           // never
+	PAGER_ALWAYS        // always (when the output is a terminal)
+)
+
+// The lines drawn by the sql format
+const (
+	BORDER_ASCII   = iota // | between columns and a line of - under the header
+	BORDER_NONE           // a space between columns
+	BORDER_UNICODE        // a box around everything
+)
+
+const DEFAULT_FOOTER_FORMAT = "(${rows})\n\nsql:${sql} opt:${opt} run:${run} clk:${clk}\n"
+
+// Returned by Set for a name which isn't a display setting
+var ErrUnknownSetting = errors.New("unknown display setting")
+
+// The settings that can be changed with Set (\pset and the config file)
+var DisplaySettings = []string{"null", "border", "header", "footer", "types", "footerFormat", "numericLocale", "pager"}
+
+// Display settings (\pset), shared by the formats which care about them
+type Display struct {
+	// Of the terminal, 0 when unknown (e.g. the output isn't a terminal), in
+	// which case tables are never narrowed.
+	Width int
+
+	Overflow int
+
+	// The sql format switches to expanded when the table doesn't fit
+	ExpandedAuto bool
+
+	// Not used by the formats, but it's a display setting like the others
+	Pager int
+
+	// Shown for NULL values (csv and tsv write nothing, unless this is changed
+	// or they have their own null option)
+	Null string
+
+	Border int
+
+	// The column names (and, for expanded, the record separators)
+	ShowHeader bool
+
+	// The type of each column, under its name
+	ShowTypes bool
+
+	// The row count and timing after each result, see RenderFooter
+	ShowFooter   bool
+	FooterFormat string
+
+	// Numbers are shown with the thousands and decimal separators of the locale
+	// (from LC_ALL, LC_NUMERIC or LANG)
+	NumericLocale bool
+}
+
+func DefaultDisplay() Display {
+	return Display{
+		Null:         "NULL",
+		ShowHeader:   true,
+		ShowFooter:   true,
+		FooterFormat: DEFAULT_FOOTER_FORMAT,
+	}
+}
+
+// Implemented by formatters that use the display settings. Called before the
+// query's results are rendered.
+type DisplayFormatter interface {
+	SetDisplay(display Display)
+}
+
+// Embedded by formatters that use the display settings
+type displayed struct {
+	display Display
+}
+
+func newDisplayed() displayed {
+	return displayed{display: DefaultDisplay()}
+}
+
+func (d *displayed) SetDisplay(display Display) {
+	d.display = display
+}
+
+// Names are case insensitive
+func (d *Display) Set(name string, value string) error {
+	switch strings.ToLower(name) {
+	case "null":
+		d.Null = value
+	case "border":
+		switch strings.ToLower(value) {
+		case "ascii":
+			d.Border = BORDER_ASCII
+		case "none":
+			d.Border = BORDER_NONE
+		case "unicode":
+			d.Border = BORDER_UNICODE
+		default:
+			return fmt.Errorf("border should be ascii, none or unicode")
+		}
+	case "header":
+		return setOnOff(&d.ShowHeader, name, value)
+	case "footer":
+		return setOnOff(&d.ShowFooter, name, value)
+	case "types":
+		return setOnOff(&d.ShowTypes, name, value)
+	case "numericlocale":
+		return setOnOff(&d.NumericLocale, name, value)
+	case "footerformat":
+		if value == "" {
+			value = DEFAULT_FOOTER_FORMAT
+		}
+		// \n is the only escape, so that a template can be given on one line
+		value = strings.ReplaceAll(value, "\\n", "\n")
+		if !strings.HasSuffix(value, "\n") {
+			value += "\n"
+		}
+		d.FooterFormat = value
+	case "pager":
+		switch strings.ToLower(value) {
+		case "on", "1", "true":
+			d.Pager = PAGER_ON
+		case "off", "0", "false":
+			d.Pager = PAGER_OFF
+		case "always":
+			d.Pager = PAGER_ALWAYS
+		default:
+			return fmt.Errorf("pager should be on, off or always")
+		}
+	default:
+		return ErrUnknownSetting
+	}
+	return nil
+}
+
+func (d *Display) Get(name string) string {
+	switch strings.ToLower(name) {
+	case "null":
+		return strconv.Quote(d.Null)
+	case "border":
+		switch d.Border {
+		case BORDER_NONE:
+			return "none"
+		case BORDER_UNICODE:
+			return "unicode"
+		}
+		return "ascii"
+	case "header":
+		return onOff(d.ShowHeader)
+	case "footer":
+		return onOff(d.ShowFooter)
+	case "types":
+		return onOff(d.ShowTypes)
+	case "numericlocale":
+		return onOff(d.NumericLocale)
+	case "footerformat":
+		return strconv.Quote(d.FooterFormat)
+	case "pager":
+		switch d.Pager {
+		case PAGER_OFF:
+			return "off"
+		case PAGER_ALWAYS:
+			return "always"
+		}
+		return "on"
+	}
+	return ""
+}
+
+// The footer shown after a result, from FooterFormat, which can use:
+//
+//	${rows}   e.g. "1 row" or "3 rows"
+//	${count}  the number of rows
+//	${sql}, ${opt}, ${run}  the server's timings, in milliseconds
+//	${clk}    the time it took us to get and render the result
+func (d *Display) RenderFooter(meta *driver.Meta, clk time.Duration) string {
+	rows := fmt.Sprintf("%d rows", meta.RowCount)
+	if meta.RowCount == 1 {
+		rows = "1 row"
+	}
+	return strings.NewReplacer(
+		"${rows}", rows,
+		"${count}", strconv.Itoa(meta.RowCount),
+		"${sql}", fmt.Sprintf("%0.3f", float32(meta.SqlTime)/1000),
+		"${opt}", fmt.Sprintf("%0.3f", float32(meta.OptTime)/1000),
+		"${run}", fmt.Sprintf("%0.3f", float32(meta.RunTime)/1000),
+		"${clk}", clk.String(),
+	).Replace(d.FooterFormat)
+}
+
+// Applies NumericLocale to the value, if it's a number
+func (d *Display) number(tpe string, value string) string {
+	if !d.NumericLocale {
+		return value
+	}
+	switch tpe {
+	case "tinyint", "smallint", "int", "bigint", "hugeint", "decimal", "real", "float", "double":
+		return localizeNumber(value)
+	}
+	return value
+}
+
+// The label of a column, with its type when ShowTypes is on, for the formats
+// which don't show the types on a line of their own
+func (d *Display) label(column string, tpe string) string {
+	if d.ShowTypes {
+		return column + " (" + tpe + ")"
+	}
+	return column
+}
+
+func setOnOff(setting *bool, name string, value string) error {
+	switch strings.ToLower(value) {
+	case "on", "1", "true":
+		*setting = true
+	case "off", "0", "false":
+		*setting = false
+	default:
+		return fmt.Errorf("%s should be on or off", name)
+	}
+	return nil
+}
+
+func onOff(on bool) string {
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// thousands and decimal separators, by language, for those which don't use
+// the default , and .
+var numericLocales = map[string][2]string{
+	"da": {".", ","}, "de": {".", ","}, "es": {".", ","}, "id": {".", ","},
+	"it": {".", ","}, "nl": {".", ","}, "pt": {".", ","}, "tr": {".", ","},
+	"cs": {" ", ","}, "fi": {" ", ","}, "fr": {" ", ","}, "nb": {" ", ","},
+	"pl": {" ", ","}, "ru": {" ", ","}, "sv": {" ", ","}, "uk": {" ", ","},
+}
+
+var thousandsSeparator, decimalSeparator = func() (string, string) {
+	locale := os.Getenv("LC_ALL")
+	if locale == "" {
+		locale = os.Getenv("LC_NUMERIC")
+	}
+	if locale == "" {
+		locale = os.Getenv("LANG")
+	}
+	// e.g. de_DE.UTF-8
+	if i := strings.IndexAny(locale, "_.@"); i != -1 {
+		locale = locale[:i]
+	}
+	if separators, ok := numericLocales[strings.ToLower(locale)]; ok {
+		return separators[0], separators[1]
+	}
+	return ",", "."
+}()
+
+// Only plain numbers (e.g. -1234.5) are changed, anything else (e.g. 1e+20) is
+// returned as-is
+func localizeNumber(value string) string {
+	sign := ""
+	if strings.HasPrefix(value, "-") {
+		sign, value = "-", value[1:]
+	}
+	integer, fraction := value, ""
+	if dot := strings.IndexByte(value, '.'); dot != -1 {
+		integer, fraction = value[:dot], value[dot+1:]
+	}
+	if integer == "" || strings.Trim(integer, "0123456789") != "" || strings.Trim(fraction, "0123456789") != "" {
+		return sign + value
+	}
+
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i, digit := range integer {
+		if i > 0 && (len(integer)-i)%3 == 0 {
+			sb.WriteString(thousandsSeparator)
+		}
+		sb.WriteRune(digit)
+	}
+	if fraction != "" || strings.HasSuffix(value, ".") {
+		sb.WriteString(decimalSeparator)
+		sb.WriteString(fraction)
+	}
+	return sb.String()
+}
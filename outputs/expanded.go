@@ -5,53 +5,75 @@ import (
 	"strconv"
 
 	"github.com/karlseguin/msql/driver"
-	"github.com/olekukonko/tablewriter"
 )
 
-func Expanded(conn driver.Conn, out io.Writer) (*driver.Meta, error) {
-	result, err := conn.ReadResult()
-	if err != nil {
-		return nil, err
-	}
+type expandedFormatter struct {
+	NoOptions
+	displayed
+}
 
-	meta := result.Meta()
-	if ok, data := result.IsSimple(); ok {
-		io.WriteString(out, data)
-		return meta, nil
-	}
+func (f *expandedFormatter) Render(result driver.Result, out io.Writer) error {
+	return renderExpanded(result, out, f.display)
+}
+
+func renderExpanded(result driver.Result, out io.Writer, display Display) error {
+	result.SetNull(display.Null)
+	return renderExpandedRows(result, nil, out, display)
+}
 
+// rows are the rows which have already been read from the result (the sql
+// format reads the first page before deciding to switch to expanded), and
+// have already been through display.number
+func renderExpandedRows(result driver.Result, rows [][]string, out io.Writer, display Display) error {
+	types := result.Types()
+	labels := make([]string, len(result.Columns()))
 	maxWidth := 0
-	for _, c := range result.Columns() {
-		if len(c) > maxWidth {
-			maxWidth = len(c)
+	for i, column := range result.Columns() {
+		labels[i] = display.label(column, types[i])
+		if width := displayWidth(labels[i]); width > maxWidth {
+			maxWidth = width
 		}
 	}
 
-	columns := make([][]byte, len(result.Columns()))
-	for i, column := range result.Columns() {
-		columns[i] = []byte("\n" + tablewriter.PadRight(column, " ", maxWidth) + " | ")
+	columns := make([][]byte, len(labels))
+	for i, label := range labels {
+		columns[i] = []byte(pad(label, maxWidth, ALIGN_LEFT) + " | ")
 	}
 
 	rowIndex := 1
+	localized := rows != nil
 	for {
-		rows, err := result.Next()
-		if err != nil {
-			return nil, err
-		}
 		if rows == nil {
-			return meta, nil
+			var err error
+			if rows, err = result.Next(); err != nil {
+				return err
+			}
+			if rows == nil {
+				return nil
+			}
 		}
 
 		for _, row := range rows {
-			io.WriteString(out, "-[ RECORD ")
-			io.WriteString(out, strconv.Itoa(rowIndex))
-			out.Write([]byte(" ] "))
+			// without the header, records are separated by a blank line
+			if display.ShowHeader {
+				io.WriteString(out, "-[ RECORD ")
+				io.WriteString(out, strconv.Itoa(rowIndex))
+				out.Write([]byte(" ] \n"))
+			} else if rowIndex > 1 {
+				out.Write([]byte("\n"))
+			}
 			for colIndex, column := range columns {
 				out.Write(column)
-				io.WriteString(out, row[colIndex])
+				value := row[colIndex]
+				if !localized {
+					value = display.number(types[colIndex], value)
+				}
+				io.WriteString(out, value)
+				out.Write([]byte("\n"))
 			}
-			out.Write([]byte("\n"))
 			rowIndex += 1
 		}
+		rows = nil
+		localized = false
 	}
 }
@@ -0,0 +1,258 @@
+package outputs
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+// Renders results in a specific format. A new Formatter is created (by the
+// Format's New) whenever its format is selected, so it can hold the options.
+type Formatter interface {
+	// Renders a single result. Should stream the rows (see eachPage) rather
+	// than load them all, since results can be large.
+	Render(result driver.Result, out io.Writer) error
+
+	// Called for each option the user sets. The name has already been checked
+	// against the Format's Options.
+	SetOption(name string, value string) error
+}
+
+// Implemented by formatters that need the query's text (e.g. as a caption).
+// Called before the query's results are rendered.
+type QueryFormatter interface {
+	SetQuery(sql string)
+}
+
+// Implemented by formatters that work on the reply's lines rather than on the
+// parsed results (like raw). When implemented, Render isn't used.
+type ReplyFormatter interface {
+	RenderReply(conn driver.Conn, out io.Writer, footer Footer) error
+}
+
+type Option struct {
+	Name        string
+	Description string
+}
+
+type Format struct {
+	Name        string
+	Label       string // e.g. "SQL", as in "SQL display is on"
+	Description string
+	Options     []Option
+
+	// The option set by a value given without a name, e.g. the table in
+	// "\f insert users"
+	Argument string
+
+	// Whether the row count and timing are shown after each result. Off for
+	// formats meant to be consumed by other tools.
+	Footer bool
+
+	// Whether responses without rows (e.g. "OK") are shown.
+	Messages bool
+
+	New func() Formatter
+}
+
+// Embedded by formatters which have no options
+type NoOptions struct{}
+
+func (_ NoOptions) SetOption(name string, value string) error {
+	return fmt.Errorf("unknown option '%s'", name)
+}
+
+var formats []Format
+
+// Formats are listed (e.g. by \?) in the order they're registered. Registering
+// a format with the name of an existing one replaces it.
+func Register(format Format) {
+	for i, existing := range formats {
+		if existing.Name == format.Name {
+			formats[i] = format
+			return
+		}
+	}
+	formats = append(formats, format)
+}
+
+func Lookup(name string) (Format, bool) {
+	for _, format := range formats {
+		if format.Name == name {
+			return format, true
+		}
+	}
+	return Format{}, false
+}
+
+func Formats() []Format {
+	return append([]Format(nil), formats...)
+}
+
+func Names() []string {
+	names := make([]string, len(formats))
+	for i, format := range formats {
+		names[i] = format.Name
+	}
+	return names
+}
+
+func SetOption(format Format, formatter Formatter, name string, value string) error {
+	if len(format.Options) == 0 {
+		return fmt.Errorf("the %s format has no options", format.Name)
+	}
+
+	names := make([]string, len(format.Options))
+	for i, option := range format.Options {
+		if option.Name == name {
+			return formatter.SetOption(name, value)
+		}
+		names[i] = option.Name
+	}
+	return fmt.Errorf("unknown %s option '%s', valid options are: %s", format.Name, name, strings.Join(names, ", "))
+}
+
+// Renders every result of the reply
+func Render(conn driver.Conn, out io.Writer, footer Footer, format Format, formatter Formatter) error {
+	if !format.Footer {
+		footer = func(*driver.Meta) {}
+	}
+	if r, ok := formatter.(ReplyFormatter); ok {
+		return r.RenderReply(conn, out, footer)
+	}
+	if format.Messages {
+		return each(conn, out, footer, formatter.Render)
+	}
+	return eachRows(conn, out, footer, formatter.Render)
+}
+
+func init() {
+	Register(Format{
+		Name:        "sql",
+		Label:       "SQL",
+		Description: "an aligned table (the default)",
+		Footer:      true,
+		Messages:    true,
+		New:         func() Formatter { return &sqlFormatter{displayed: newDisplayed()} },
+	})
+	Register(Format{
+		Name:        "expanded",
+		Label:       "Expanded",
+		Description: "each column of each row on its own line",
+		Footer:      true,
+		Messages:    true,
+		New:         func() Formatter { return &expandedFormatter{displayed: newDisplayed()} },
+	})
+	Register(Format{
+		Name:        "raw",
+		Label:       "Raw",
+		Description: "the server's response, as-is",
+		Footer:      true,
+		Messages:    true,
+		New:         func() Formatter { return rawFormatter{} },
+	})
+	Register(Format{
+		Name:        "trash",
+		Label:       "Trash",
+		Description: "nothing, only the row count and timing",
+		Footer:      true,
+		Messages:    true,
+		New:         func() Formatter { return trashFormatter{} },
+	})
+	Register(Format{
+		Name:        "csv",
+		Label:       "CSV",
+		Description: "comma separated values",
+		Options:     csvOptions,
+		New:         func() Formatter { return &csvFormatter{options: DefaultCSVOptions(), displayed: newDisplayed()} },
+	})
+	Register(Format{
+		Name:        "tsv",
+		Label:       "TSV",
+		Description: "tab separated values",
+		Options:     csvOptions,
+		New:         func() Formatter { return &csvFormatter{options: DefaultTSVOptions(), displayed: newDisplayed()} },
+	})
+	Register(Format{
+		Name:        "json",
+		Label:       "JSON",
+		Description: "an array of objects",
+		Options:     jsonOptions,
+		New:         func() Formatter { return &jsonFormatter{} },
+	})
+	Register(Format{
+		Name:        "ndjson",
+		Label:       "NDJSON",
+		Description: "an object per line",
+		Options:     jsonOptions,
+		New:         func() Formatter { return &jsonFormatter{lines: true} },
+	})
+	Register(Format{
+		Name:        "markdown",
+		Label:       "Markdown",
+		Description: "a github flavored markdown table",
+		Options:     captionOptions,
+		Messages:    true,
+		New:         func() Formatter { return &markdownFormatter{displayed: newDisplayed()} },
+	})
+	Register(Format{
+		Name:        "html",
+		Label:       "HTML",
+		Description: "an html table",
+		Options:     captionOptions,
+		Messages:    true,
+		New:         func() Formatter { return &htmlFormatter{displayed: newDisplayed()} },
+	})
+	Register(Format{
+		Name:        "asciidoc",
+		Label:       "AsciiDoc",
+		Description: "an asciidoc table",
+		Options:     captionOptions,
+		Messages:    true,
+		New:         func() Formatter { return &asciidocFormatter{displayed: newDisplayed()} },
+	})
+	Register(Format{
+		Name:        "insert",
+		Label:       "Insert",
+		Description: "insert statements",
+		Options:     insertOptions,
+		Argument:    "table",
+		New:         func() Formatter { return &insertFormatter{options: DefaultInsertOptions()} },
+	})
+}
+
+var captionOptions = []Option{
+	{"caption", "on|off, include the query text as the table's caption"},
+}
+
+// Embedded by formatters with the caption option
+type captioned struct {
+	on    bool
+	query string
+}
+
+func (c *captioned) SetOption(name string, value string) error {
+	if name == "caption" {
+		c.on = isOn(value)
+	}
+	return nil
+}
+
+func (c *captioned) SetQuery(sql string) {
+	c.query = sql
+}
+
+func (c *captioned) caption() string {
+	if !c.on {
+		return ""
+	}
+	return c.query
+}
+
+// shared by the options of various formats
+func isOn(value string) bool {
+	value = strings.ToLower(value)
+	return value == "on" || value == "1" || value == "true"
+}
@@ -0,0 +1,83 @@
+package outputs
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"strings"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+type htmlFormatter struct {
+	captioned
+	displayed
+}
+
+func (f *htmlFormatter) Render(result driver.Result, out io.Writer) error {
+	return renderHTML(result, out, f.caption(), f.display)
+}
+
+func renderHTML(result driver.Result, out io.Writer, caption string, display Display) error {
+	result.SetNull(display.Null)
+	types := result.Types()
+
+	w := bufio.NewWriter(out)
+	w.WriteString("<table>\n")
+	if caption != "" {
+		w.WriteString("<caption>")
+		w.WriteString(htmlEscape(strings.TrimSpace(caption)))
+		w.WriteString("</caption>\n")
+	}
+
+	writeHeader := func(row []string) {
+		w.WriteString("<tr>")
+		for _, value := range row {
+			w.WriteString("<th>")
+			w.WriteString(htmlEscape(value))
+			w.WriteString("</th>")
+		}
+		w.WriteString("</tr>\n")
+	}
+
+	if display.ShowHeader {
+		w.WriteString("<thead>\n")
+		writeHeader(result.Columns())
+		if display.ShowTypes {
+			writeHeader(types)
+		}
+		w.WriteString("</thead>\n")
+	}
+	w.WriteString("<tbody>\n")
+
+	cells := make([]string, len(types))
+	for i, tpe := range types {
+		if isNumeric(tpe) {
+			cells[i] = `<td style="text-align: right">`
+		} else {
+			cells[i] = "<td>"
+		}
+	}
+
+	err := eachPage(result, w, func(rows [][]string) {
+		for _, row := range rows {
+			w.WriteString("<tr>")
+			for i, value := range row {
+				w.WriteString(cells[i])
+				w.WriteString(htmlEscape(display.number(types[i], value)))
+				w.WriteString("</td>")
+			}
+			w.WriteString("</tr>\n")
+		}
+	})
+	if err != nil {
+		return err
+	}
+	io.WriteString(out, "</tbody>\n</table>\n")
+	return nil
+}
+
+// newlines would otherwise be collapsed
+func htmlEscape(value string) string {
+	return strings.ReplaceAll(html.EscapeString(value), "\n", "<br>")
+}
@@ -0,0 +1,136 @@
+package outputs
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+type InsertOptions struct {
+	// defaults to the table of the result's first column
+	Table string
+
+	// number of rows per insert statement
+	Batch int
+}
+
+func DefaultInsertOptions() InsertOptions {
+	return InsertOptions{Batch: 1}
+}
+
+var insertOptions = []Option{
+	{"table", "the table to insert into (defaults to the result's table)"},
+	{"batch", "the number of rows per insert statement (defaults to 1)"},
+}
+
+type insertFormatter struct {
+	options InsertOptions
+}
+
+func (f *insertFormatter) Render(result driver.Result, out io.Writer) error {
+	return renderInsert(result, out, f.options)
+}
+
+func (f *insertFormatter) SetOption(name string, value string) error {
+	switch name {
+	case "table":
+		f.options.Table = value
+	case "batch":
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return fmt.Errorf("batch must be a positive number, got '%s'", value)
+		}
+		f.options.Batch = n
+	}
+	return nil
+}
+
+func renderInsert(result driver.Result, out io.Writer, options InsertOptions) error {
+	table := options.Table
+	if table == "" {
+		var err error
+		if table, err = resultTable(result); err != nil {
+			return err
+		}
+	}
+
+	batch := options.Batch
+	if batch < 1 {
+		batch = 1
+	}
+
+	columns := make([]string, len(result.Columns()))
+	for i, column := range result.Columns() {
+		columns[i] = driver.QuoteIdentifier(column)
+	}
+	prefix := "insert into " + table + " (" + strings.Join(columns, ", ") + ") values"
+
+	result.SetNull(null)
+	types := result.Types()
+
+	// rows in the current statement, carried across pages
+	count := 0
+	w := bufio.NewWriter(out)
+	err := eachPage(result, w, func(rows [][]string) {
+		for _, row := range rows {
+			if count == 0 {
+				w.WriteString(prefix)
+			} else {
+				w.WriteString(",")
+			}
+			if batch == 1 {
+				w.WriteString(" (")
+			} else {
+				w.WriteString("\n(")
+			}
+			for i, value := range row {
+				if i > 0 {
+					w.WriteString(", ")
+				}
+				if value == null {
+					w.WriteString("NULL")
+				} else {
+					w.WriteString(driver.Literal(types[i], value))
+				}
+			}
+			w.WriteString(")")
+
+			count += 1
+			if count == batch {
+				w.WriteString(";\n")
+				count = 0
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		io.WriteString(out, ";\n")
+	}
+	return nil
+}
+
+// The table the result came from, which is only known if the first column is
+// an actual column of a table (and not, say, count(*))
+func resultTable(result driver.Result) (string, error) {
+	tables := result.Tables()
+	if len(tables) > 0 {
+		schema, table := "", tables[0]
+		if dot := strings.IndexByte(table, '.'); dot != -1 {
+			schema, table = table[:dot], table[dot+1:]
+		}
+		if table != "" && table[0] != '%' {
+			if schema == "" {
+				return driver.QuoteIdentifier(table), nil
+			}
+			return driver.QuoteIdentifier(schema) + "." + driver.QuoteIdentifier(table), nil
+		}
+	}
+	return "", fmt.Errorf("cannot determine the table to insert into, use: \\f insert TABLE")
+}
@@ -0,0 +1,44 @@
+package outputs
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_Insert(t *testing.T) {
+	result := func() *fakeResult {
+		return &fakeResult{
+			columns: []string{"id", "user", "Order", "say \"hi\""},
+			types:   []string{"int", "varchar", "date", "varchar"},
+			tables:  []string{"sys.order", "sys.order", "sys.order", "sys.order"},
+			pages: [][][]string{
+				{{"1", "it's", "2020-01-02", "NULL"}},
+				{{"2", "NULL", "NULL", "x"}},
+			},
+		}
+	}
+
+	cases := []struct {
+		name    string
+		options InsertOptions
+		want    string
+	}{
+		{"default", DefaultInsertOptions(), `insert into "sys"."order" ("id", "user", "Order", "say ""hi""") values (1, 'it\'s', date '2020-01-02', NULL);
+insert into "sys"."order" ("id", "user", "Order", "say ""hi""") values (2, NULL, NULL, 'x');
+`},
+		{"batch", InsertOptions{Table: `"select"`, Batch: 5}, `insert into "select" ("id", "user", "Order", "say ""hi""") values
+(1, 'it\'s', date '2020-01-02', NULL),
+(2, NULL, NULL, 'x');
+`},
+	}
+
+	for _, c := range cases {
+		var out strings.Builder
+		if err := renderInsert(result(), &out, c.options); err != nil {
+			t.Fatal(err)
+		}
+		if got := out.String(); got != c.want {
+			t.Errorf("%s:\n  got:  %s\n  want: %s", c.name, got, c.want)
+		}
+	}
+}
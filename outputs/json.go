@@ -0,0 +1,160 @@
+package outputs
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+type JSONOptions struct {
+	// write a trailing object with the column types, row count and timings
+	Meta bool
+}
+
+var jsonOptions = []Option{
+	{"meta", "on|off, output a trailing object with the column types, row count and timings"},
+}
+
+// json writes each result as an array of objects, ndjson (lines) writes each
+// row as an object on its own line
+type jsonFormatter struct {
+	lines   bool
+	options JSONOptions
+}
+
+func (f *jsonFormatter) Render(result driver.Result, out io.Writer) error {
+	return renderJSON(result, out, f.options, f.lines)
+}
+
+func (f *jsonFormatter) SetOption(name string, value string) error {
+	if name == "meta" {
+		f.options.Meta = isOn(value)
+	}
+	return nil
+}
+
+func renderJSON(result driver.Result, out io.Writer, options JSONOptions, lines bool) error {
+	result.SetNull(null)
+	types := result.Types()
+
+	// json.Marshal would escape <, > and &, which is valid, but noisy
+	var scratch bytes.Buffer
+	encoder := json.NewEncoder(&scratch)
+	encoder.SetEscapeHTML(false)
+	quote := func(s string) []byte {
+		scratch.Reset()
+		encoder.Encode(s)
+		// copied, since scratch is reused
+		return append([]byte(nil), bytes.TrimSuffix(scratch.Bytes(), []byte("\n"))...)
+	}
+
+	keys := make([][]byte, len(result.Columns()))
+	for i, column := range result.Columns() {
+		keys[i] = append(quote(column), ':')
+	}
+
+	w := bufio.NewWriter(out)
+	if !lines {
+		w.WriteByte('[')
+	}
+
+	first := true
+	for {
+		rows, err := result.Next()
+		if err != nil {
+			w.Flush()
+			return err
+		}
+		if rows == nil {
+			break
+		}
+		for _, row := range rows {
+			if !lines {
+				if first {
+					w.WriteByte('\n')
+				} else {
+					w.WriteString(",\n")
+				}
+				first = false
+			}
+
+			w.WriteByte('{')
+			for i, value := range row {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				w.Write(keys[i])
+				w.Write(jsonValue(types[i], value, quote))
+			}
+			w.WriteByte('}')
+			if lines {
+				w.WriteByte('\n')
+			}
+		}
+		// flush every page so that the output streams
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if !lines {
+		w.WriteString("\n]\n")
+	}
+	if options.Meta {
+		w.Write(jsonMeta(result))
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
+
+// Numbers and booleans are written as-is, json columns are embedded and
+// everything else is a string. The server's text is kept as-is (rather than
+// going through NextValues) so that dates, times and decimals don't change.
+func jsonValue(tpe string, value string, quote func(string) []byte) []byte {
+	if value == null {
+		return []byte("null")
+	}
+
+	switch tpe {
+	case "tinyint", "smallint", "int", "bigint", "hugeint", "decimal", "real", "float", "double",
+		"sec_interval", "day_interval", "month_interval", "boolean", "json":
+		// guards against things like nan and inf, which json doesn't support
+		if json.Valid([]byte(value)) {
+			return []byte(value)
+		}
+	}
+	return quote(value)
+}
+
+func jsonMeta(result driver.Result) []byte {
+	type column struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	type meta struct {
+		Columns  []column `json:"columns"`
+		RowCount int      `json:"rowCount"`
+		SqlTime  int      `json:"sqlTime"`
+		OptTime  int      `json:"optTime"`
+		RunTime  int      `json:"runTime"`
+	}
+
+	m := meta{Columns: make([]column, len(result.Columns()))}
+	for i, name := range result.Columns() {
+		m.Columns[i] = column{Name: name, Type: result.Types()[i]}
+	}
+	if rm := result.Meta(); rm != nil {
+		m.RowCount = rm.RowCount
+		m.SqlTime = rm.SqlTime
+		m.OptTime = rm.OptTime
+		m.RunTime = rm.RunTime
+	}
+
+	data, _ := json.Marshal(struct {
+		Meta meta `json:"meta"`
+	}{m})
+	return data
+}
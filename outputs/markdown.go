@@ -0,0 +1,75 @@
+package outputs
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+var markdownEscaper = strings.NewReplacer("\\", "\\\\", "|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+// A github flavored markdown table. Markdown tables have no caption, so the
+// caption (if any) is written as a sql code block above the table.
+type markdownFormatter struct {
+	captioned
+	displayed
+}
+
+func (f *markdownFormatter) Render(result driver.Result, out io.Writer) error {
+	return renderMarkdown(result, out, f.caption(), f.display)
+}
+
+// A markdown table can't be without a header row, so it's always there. Types
+// are part of the column names (markdown has no second header row).
+func renderMarkdown(result driver.Result, out io.Writer, caption string, display Display) error {
+	result.SetNull(display.Null)
+	types := result.Types()
+
+	w := bufio.NewWriter(out)
+	if caption != "" {
+		w.WriteString("```sql\n")
+		w.WriteString(strings.TrimSpace(caption))
+		w.WriteString("\n```\n\n")
+	}
+
+	writeRow := func(row []string) {
+		w.WriteString("|")
+		for _, value := range row {
+			w.WriteString(" ")
+			w.WriteString(markdownEscaper.Replace(value))
+			w.WriteString(" |")
+		}
+		w.WriteString("\n")
+	}
+
+	labels := make([]string, len(types))
+	for i, column := range result.Columns() {
+		labels[i] = display.label(column, types[i])
+	}
+	writeRow(labels)
+	w.WriteString("|")
+	for _, tpe := range types {
+		if isNumeric(tpe) {
+			w.WriteString(" ---: |")
+		} else {
+			w.WriteString(" --- |")
+		}
+	}
+	w.WriteString("\n")
+
+	err := eachPage(result, w, func(rows [][]string) {
+		for _, row := range rows {
+			for i, value := range row {
+				row[i] = display.number(types[i], value)
+			}
+			writeRow(row)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	io.WriteString(out, "\n")
+	return nil
+}
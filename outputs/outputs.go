@@ -0,0 +1,109 @@
+package outputs
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+// Passed to SetNull by outputs that need to tell NULL apart from any string.
+// MonetDB strings can't contain a NUL, so this can't be mistaken for a value.
+const null = "\x00"
+
+// Called after each result of a reply has been rendered, so that the caller
+// can output the row count and timing of that result. meta can be nil.
+type Footer func(meta *driver.Meta)
+
+// A reply can contain multiple results (e.g. "select 1; select 2;"). Renders
+// each one in turn, calling footer after each.
+func each(conn driver.Conn, out io.Writer, footer Footer, render func(driver.Result, io.Writer) error) error {
+	for {
+		result, err := conn.NextResult()
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			return nil
+		}
+
+		if ok, data := result.IsSimple(); ok {
+			io.WriteString(out, data)
+		} else if err := render(result, out); err != nil {
+			return err
+		}
+		footer(result.Meta())
+	}
+}
+
+// Like each, but responses without rows (e.g. "OK" or "1 affected row") are
+// skipped, for outputs meant to be consumed by other tools (like json).
+func eachRows(conn driver.Conn, out io.Writer, footer Footer, render func(driver.Result, io.Writer) error) error {
+	for {
+		result, err := conn.NextResult()
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			return nil
+		}
+
+		if ok, _ := result.IsSimple(); !ok {
+			if err := render(result, out); err != nil {
+				return err
+			}
+		}
+		footer(result.Meta())
+	}
+}
+
+// Raw and trash don't parse the results, but still need to know where one
+// result ends and the next begins (and get its meta). Calls fn for every line
+// of the reply.
+func eachLine(conn driver.Conn, footer Footer, fn func(line []byte)) error {
+	var meta *driver.Meta
+	started := false
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return err
+		}
+		if line == nil {
+			footer(meta)
+			return nil
+		}
+
+		if len(line) > 0 && line[0] == '&' {
+			if started {
+				footer(meta)
+			}
+			started = true
+			meta = driver.NewMeta(line)
+		}
+		fn(line)
+	}
+}
+
+// numeric values are aligned right, everything else is aligned left
+func isNumeric(tpe string) bool {
+	return tpe == "tinyint" || tpe == "smallint" || tpe == "int" || tpe == "bigint" || tpe == "hugeint" || tpe == "real" || tpe == "float" || tpe == "double"
+}
+
+// Calls fn for each page of rows as they're received, flushing after each so
+// that the output streams.
+func eachPage(result driver.Result, w *bufio.Writer, fn func(rows [][]string)) error {
+	for {
+		rows, err := result.Next()
+		if err != nil {
+			w.Flush()
+			return err
+		}
+		if rows == nil {
+			return w.Flush()
+		}
+		fn(rows)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+}
@@ -6,17 +6,19 @@ import (
 	"github.com/karlseguin/msql/driver"
 )
 
-func Raw(conn driver.Conn, out io.Writer) (*driver.Meta, error) {
-	data, fin, err := conn.ReadFrame()
-	meta := driver.NewMeta(data)
-	for {
-		if err != nil {
-			return nil, err
-		}
-		out.Write(data)
-		if fin {
-			return meta, nil
-		}
-		data, fin, err = conn.ReadFrame()
-	}
+type rawFormatter struct {
+	NoOptions
+}
+
+func (_ rawFormatter) RenderReply(conn driver.Conn, out io.Writer, footer Footer) error {
+	newline := []byte("\n")
+	return eachLine(conn, footer, func(line []byte) {
+		out.Write(line)
+		out.Write(newline)
+	})
+}
+
+// Unused, since RenderReply is implemented
+func (_ rawFormatter) Render(result driver.Result, out io.Writer) error {
+	return nil
 }
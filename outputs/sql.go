@@ -1,104 +1,369 @@
 package outputs
 
 import (
+	"bufio"
 	"io"
+	"strings"
 
 	"github.com/karlseguin/msql/driver"
-	"github.com/olekukonko/tablewriter"
 )
 
-// We want to stream data as it's received (one frame at a time), but our table
-// renderer doesn't support streaming.
-// Our problem is with column widths. The table renderer takes all the data and
-// figures out the correct width. That won't work if the data comes in chunks.
-// Thankfully, monetdb server returns the max length of each column in its header.
-// With this information we can:
-// 1 - Render each frame as a distinct table
-// 2 - Only render the header on the first table
-// 3 - Pad the first row of each frame to the max width to generate a consistent
-//     layout across the table renders.
-func SQL(conn driver.Conn, out io.Writer) (*driver.Meta, error) {
-	result, err := conn.ReadResult()
-	if err != nil {
-		return nil, err
-	}
-
-	meta := result.Meta()
-	if ok, data := result.IsSimple(); ok {
-		io.WriteString(out, data)
-		return meta, nil
-	}
-
-	// We need to pad to whichever is wider: the column width or value widths
+// columns aren't narrowed below this (when fitting the table to the terminal)
+const MIN_COLUMN_WIDTH = 3
+
+const (
+	ALIGN_LEFT = iota
+	ALIGN_RIGHT
+	ALIGN_CENTER
+)
+
+// We want to stream data as it's received (one frame at a time), but to line up
+// the table we need the width of each column before rendering the first row.
+// Thankfully, monetdb server returns the max length of each column in its
+// header. Along with the display width of the values in the first page, this
+// gives us widths which we then keep for the rest of the result (a wider value
+// in a later page is wrapped).
+// If the terminal's width is known, the table can be narrowed to fit it by
+// wrapping or truncating values (see Display).
+type sqlFormatter struct {
+	NoOptions
+	displayed
+}
+
+func (f *sqlFormatter) Render(result driver.Result, out io.Writer) error {
+	return renderSQL(result, out, f.display)
+}
+
+func renderSQL(result driver.Result, out io.Writer, display Display) error {
+	result.SetNull(display.Null)
+	rows, err := result.Next()
+	if err != nil || rows == nil {
+		return err
+	}
+
 	columns := result.Columns()
-	lengths := result.Lengths()
-	for i, length := range lengths {
-		columnLength := len(columns[i])
-		if columnLength > length {
-			lengths[i] = columnLength
+	types := result.Types()
+	localize := func(rows [][]string) {
+		if display.NumericLocale {
+			for _, row := range rows {
+				for i, value := range row {
+					row[i] = display.number(types[i], value)
+				}
+			}
+		}
+	}
+	localize(rows)
+
+	var headers [][]string
+	if display.ShowHeader {
+		headers = append(headers, columns)
+		if display.ShowTypes {
+			headers = append(headers, types)
+		}
+	}
+
+	border := borders[display.Border]
+	widths := columnWidths(headers, result.Lengths(), rows)
+
+	overflow := OVERFLOW_NONE
+	if display.Width > 0 {
+		if display.ExpandedAuto && border.width(widths) > display.Width {
+			return renderExpandedRows(result, rows, out, display)
+		}
+		overflow = display.Overflow
+		if overflow != OVERFLOW_NONE {
+			narrow(widths, border, display.Width)
+		}
+	}
+
+	// numeric values are aligned right, everything else left
+	aligns := make([]int, len(columns))
+	headerAligns := make([]int, len(columns))
+	for i, tpe := range types {
+		if isNumeric(tpe) {
+			aligns[i] = ALIGN_RIGHT
+		}
+		headerAligns[i] = ALIGN_CENTER
+	}
+
+	w := bufio.NewWriter(out)
+	t := &sqlTable{w: w, widths: widths, border: border, overflow: overflow}
+
+	t.line(border.top)
+	for _, header := range headers {
+		t.row(header, headerAligns)
+	}
+	if len(headers) > 0 {
+		t.line(border.middle)
+	}
+
+	// the header has been printed with these widths, values of later pages
+	// which don't fit are wrapped (unless they're being truncated)
+	if t.overflow == OVERFLOW_NONE {
+		t.overflow = OVERFLOW_WRAP
+	}
+	writeRows := func(rows [][]string) {
+		localize(rows)
+		for _, row := range rows {
+			t.row(row, aligns)
+		}
+	}
+	for _, row := range rows {
+		t.row(row, aligns)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if err := eachPage(result, w, writeRows); err != nil {
+		return err
+	}
+	t.line(border.bottom)
+	return w.Flush()
+}
+
+type borderLine struct {
+	left  string
+	fill  string
+	sep   string
+	right string
+}
+
+type border struct {
+	// around and between the cells of a row
+	left  string
+	sep   string
+	right string
+
+	// a space on either side of each cell
+	padding bool
+
+	// lines above the header, under the header and under the last row (nil
+	// for no line)
+	top    *borderLine
+	middle *borderLine
+	bottom *borderLine
+}
+
+var borders = map[int]border{
+	BORDER_ASCII: border{
+		sep:     "|",
+		padding: true,
+		middle:  &borderLine{fill: "-", sep: "|"},
+	},
+	BORDER_NONE: border{
+		sep:    " ",
+		middle: &borderLine{fill: "-", sep: " "},
+	},
+	BORDER_UNICODE: border{
+		left:    "│",
+		sep:     "│",
+		right:   "│",
+		padding: true,
+		top:     &borderLine{"┌", "─", "┬", "┐"},
+		middle:  &borderLine{"├", "─", "┼", "┤"},
+		bottom:  &borderLine{"└", "─", "┴", "┘"},
+	},
+}
+
+// The width of the table, given the width of its columns
+func (b border) width(widths []int) int {
+	total := displayWidth(b.left) + displayWidth(b.right) + displayWidth(b.sep)*(len(widths)-1)
+	for _, width := range widths {
+		total += width
+		if b.padding {
+			total += 2
 		}
 	}
+	return total
+}
+
+type sqlTable struct {
+	w        *bufio.Writer
+	widths   []int
+	border   border
+	overflow int
+}
 
-	// numeric values are padded left
-	// other values are padded right
-	padRight := make([]bool, len(result.Types()))
-	for i, tpe := range result.Types() {
-		padRight[i] = !(tpe == "tinyint" || tpe == "smallint" || tpe == "int" || tpe == "bigint" || tpe == "hugeint" || tpe == "real" || tpe == "float" || tpe == "double")
+func (t *sqlTable) line(line *borderLine) {
+	if line == nil {
+		return
+	}
+	padding := 0
+	if t.border.padding {
+		padding = 2
 	}
 
-	_, err = renderSQLPage(result, padRight, lengths, true, out)
-	if err != nil {
-		return nil, err
+	w := t.w
+	w.WriteString(line.left)
+	for i, width := range t.widths {
+		if i > 0 {
+			w.WriteString(line.sep)
+		}
+		w.WriteString(strings.Repeat(line.fill, width+padding))
+	}
+	w.WriteString(line.right)
+	w.WriteByte('\n')
+}
+
+// Writes a cell with the border that comes before it
+func (t *sqlTable) cell(i int, value string, align int) {
+	w := t.w
+	if i == 0 {
+		w.WriteString(t.border.left)
+	} else {
+		w.WriteString(t.border.sep)
+	}
+	if t.border.padding {
+		w.WriteByte(' ')
+	}
+	writePadded(w, value, t.widths[i], align)
+	if t.border.padding {
+		w.WriteByte(' ')
+	}
+}
+
+// A value can span multiple lines, because it has newlines or because it's
+// been wrapped, so a row can too.
+func (t *sqlTable) row(values []string, aligns []int) {
+	w := t.w
+	if t.simple(values) {
+		for i, value := range values {
+			t.cell(i, value, aligns[i])
+		}
+		w.WriteString(t.border.right)
+		w.WriteByte('\n')
+		return
 	}
 
-	for {
-		more, err := renderSQLPage(result, padRight, lengths, false, out)
-		if err != nil {
-			return meta, err
+	height := 1
+	cells := make([][]string, len(values))
+	for i, value := range values {
+		cells[i] = cellLines(value, t.widths[i], t.overflow)
+		if len(cells[i]) > height {
+			height = len(cells[i])
 		}
-		if !more {
-			break
+	}
+
+	for l := 0; l < height; l++ {
+		for i, lines := range cells {
+			line := ""
+			if l < len(lines) {
+				line = lines[l]
+			}
+			t.cell(i, line, aligns[i])
 		}
+		w.WriteString(t.border.right)
+		w.WriteByte('\n')
 	}
+}
 
-	return meta, nil
+// Most rows fit on a single line, which can be written without splitting (and
+// allocating) anything
+func (t *sqlTable) simple(values []string) bool {
+	for i, value := range values {
+		if strings.IndexByte(value, '\n') != -1 {
+			return false
+		}
+		if t.overflow != OVERFLOW_NONE && len(value) > t.widths[i] && displayWidth(value) > t.widths[i] {
+			return false
+		}
+	}
+	return true
 }
 
-func renderSQLPage(result driver.Result, padRight []bool, lengths []int, showHeaders bool, out io.Writer) (bool, error) {
-	data, err := result.Next()
-	if err != nil {
-		return false, err
+// The width of each column is the widest of its header (name and type), the
+// length the server says it is and the display width of its values in the
+// first page. The server gives us lengths in characters, but some characters
+// (e.g. CJK and emojis) take 2 columns.
+func columnWidths(headers [][]string, lengths []int, rows [][]string) []int {
+	widths := make([]int, len(lengths))
+	copy(widths, lengths)
+	for _, row := range append(headers, rows...) {
+		for i, value := range row {
+			for _, line := range strings.Split(value, "\n") {
+				if width := displayWidth(line); width > widths[i] {
+					widths[i] = width
+				}
+			}
+		}
 	}
+	return widths
+}
 
-	if data == nil {
-		return false, nil
+// Takes from the widest columns until the table fits (or can't be narrowed any
+// further)
+func narrow(widths []int, border border, max int) {
+	for excess := border.width(widths) - max; excess > 0; excess-- {
+		widest := 0
+		for i, width := range widths {
+			if width > widths[widest] {
+				widest = i
+			}
+		}
+		if widths[widest] <= MIN_COLUMN_WIDTH {
+			return
+		}
+		widths[widest] -= 1
 	}
+}
 
-	first := data[0]
-	for i, d := range first {
-		if padRight[i] {
-			first[i] = tablewriter.PadRight(d, " ", lengths[i])
-		} else {
-			first[i] = tablewriter.PadLeft(d, " ", lengths[i])
+func cellLines(value string, width int, overflow int) []string {
+	lines := strings.Split(value, "\n")
+	switch overflow {
+	case OVERFLOW_TRUNCATE:
+		for i, line := range lines {
+			lines[i] = truncate(line, width)
+		}
+	case OVERFLOW_WRAP:
+		wrapped := make([]string, 0, len(lines))
+		for _, line := range lines {
+			wrapped = append(wrapped, wrap(line, width)...)
 		}
+		lines = wrapped
 	}
-	data[0] = first
+	return lines
+}
 
-	table := tablewriter.NewWriter(out)
-	table.SetAutoFormatHeaders(false)
-	table.SetColWidth(72)
-	table.SetHeaderLine(true)
-	table.SetAutoWrapText(false)
-	table.SetReflowDuringAutoWrap(false)
-	table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
-	table.SetCenterSeparator("|")
+var spaces = strings.Repeat(" ", 256)
 
-	if showHeaders {
-		table.SetHeader(result.Columns())
+// Like pad, but writes to w directly
+func writePadded(w *bufio.Writer, value string, width int, align int) {
+	gap := width - displayWidth(value)
+	if gap <= 0 {
+		w.WriteString(value)
+		return
 	}
+	left := 0
+	switch align {
+	case ALIGN_RIGHT:
+		left = gap
+	case ALIGN_CENTER:
+		left = gap / 2
+	}
+	writeSpaces(w, left)
+	w.WriteString(value)
+	writeSpaces(w, gap-left)
+}
 
-	table.AppendBulk(data)
-	table.Render()
+func writeSpaces(w *bufio.Writer, n int) {
+	for n > len(spaces) {
+		w.WriteString(spaces)
+		n -= len(spaces)
+	}
+	w.WriteString(spaces[:n])
+}
 
-	return true, nil
+func pad(value string, width int, align int) string {
+	gap := width - displayWidth(value)
+	if gap <= 0 {
+		return value
+	}
+	switch align {
+	case ALIGN_RIGHT:
+		return strings.Repeat(" ", gap) + value
+	case ALIGN_CENTER:
+		left := gap / 2
+		return strings.Repeat(" ", left) + value + strings.Repeat(" ", gap-left)
+	}
+	return value + strings.Repeat(" ", gap)
 }
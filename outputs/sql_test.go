@@ -0,0 +1,220 @@
+package outputs
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/karlseguin/msql/driver"
+	"github.com/olekukonko/tablewriter"
+)
+
+// A driver.Result which returns its pages (frames) one at a time. Like the
+// server, "NULL" is a NULL (which SetNull can change).
+type fakeResult struct {
+	driver.OKResult
+	columns []string
+	types   []string
+	tables  []string
+	lengths []int
+	pages   [][][]string
+	null    *string
+}
+
+func (r *fakeResult) Columns() []string { return r.columns }
+func (r *fakeResult) Types() []string   { return r.types }
+func (r *fakeResult) Tables() []string  { return r.tables }
+func (r *fakeResult) Lengths() []int    { return r.lengths }
+func (r *fakeResult) IsSimple() (bool, string) {
+	return false, ""
+}
+
+func (r *fakeResult) SetNull(null string) {
+	r.null = &null
+}
+
+func (r *fakeResult) Next() ([][]string, error) {
+	if len(r.pages) == 0 {
+		return nil, nil
+	}
+	rows := r.pages[0]
+	r.pages = r.pages[1:]
+	if r.null != nil {
+		for _, row := range rows {
+			for i, value := range row {
+				if value == "NULL" {
+					row[i] = *r.null
+				}
+			}
+		}
+	}
+	return rows, nil
+}
+
+func renderSQLString(t *testing.T, result driver.Result) string {
+	var out strings.Builder
+	if err := renderSQL(result, &out, DefaultDisplay()); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func Test_SQL_KeepsTheWidthsOfTheFirstPage(t *testing.T) {
+	result := &fakeResult{
+		columns: []string{"id", "name"},
+		types:   []string{"int", "varchar"},
+		lengths: []int{2, 4},
+		pages: [][][]string{
+			{{"1", "abc"}},
+			{{"22345", "a much longer value"}},
+		},
+	}
+	lines := strings.Split(strings.TrimRight(renderSQLString(t, result), "\n"), "\n")
+	width := displayWidth(lines[0])
+	for _, line := range lines {
+		if displayWidth(line) != width {
+			t.Fatalf("line %q isn't %d wide:\n%s", line, width, strings.Join(lines, "\n"))
+		}
+	}
+	if len(lines) <= 4 {
+		t.Fatalf("expected the wider values to be wrapped:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+// The first page is localized before the switch to expanded, and mustn't be
+// localized again (with . for thousands, 1.234 would then read as 1,234)
+func Test_SQL_ExpandedAutoLocalizesOnce(t *testing.T) {
+	thousands, decimal := thousandsSeparator, decimalSeparator
+	thousandsSeparator, decimalSeparator = ".", ","
+	defer func() { thousandsSeparator, decimalSeparator = thousands, decimal }()
+
+	result := &fakeResult{
+		columns: []string{"id", "description"},
+		types:   []string{"int", "varchar"},
+		lengths: []int{4, 40},
+		pages: [][][]string{
+			{{"1234", strings.Repeat("wide ", 8)}},
+			{{"5678", "narrow"}},
+		},
+	}
+	display := DefaultDisplay()
+	display.NumericLocale = true
+	display.ExpandedAuto = true
+	display.Width = 20
+
+	var out strings.Builder
+	if err := renderSQL(result, &out, display); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"id          | 1.234\n", "id          | 5.678\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("expected %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+// 100,000 rows, in 1000 pages of 100 (like the frames of a large result)
+func benchmarkPages() [][][]string {
+	const rowsPerPage = 100
+	pages := make([][][]string, 1000)
+	for i := range pages {
+		pages[i] = make([][]string, rowsPerPage)
+		for j := range pages[i] {
+			n := i*rowsPerPage + j
+			pages[i][j] = []string{fmt.Sprint(n), fmt.Sprintf("name %d", n%1000), "12.25", fmt.Sprintf("some note about row %d", n)}
+		}
+	}
+	return pages
+}
+
+func benchmarkResult(pages [][][]string) *fakeResult {
+	// the renderers change the rows they're given, so each run gets a copy
+	copied := make([][][]string, len(pages))
+	for i, page := range pages {
+		copied[i] = make([][]string, len(page))
+		for j, row := range page {
+			copied[i][j] = append([]string(nil), row...)
+		}
+	}
+	return &fakeResult{
+		columns: []string{"id", "name", "price", "note"},
+		types:   []string{"int", "varchar", "double", "varchar"},
+		lengths: []int{6, 8, 5, 26},
+		pages:   copied,
+	}
+}
+
+func Benchmark_SQL_Render(b *testing.B) {
+	pages := benchmarkPages()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		result := benchmarkResult(pages)
+		b.StartTimer()
+		if err := renderSQL(result, ioutil.Discard, DefaultDisplay()); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// The renderer sql used to have, a tablewriter per page, as a baseline for
+// Benchmark_SQL_Render
+func Benchmark_SQL_Tablewriter(b *testing.B) {
+	pages := benchmarkPages()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		result := benchmarkResult(pages)
+		b.StartTimer()
+		if err := renderTablewriter(result, ioutil.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func renderTablewriter(result driver.Result, out io.Writer) error {
+	columns := result.Columns()
+	lengths := result.Lengths()
+	for i, length := range lengths {
+		if columnLength := len(columns[i]); columnLength > length {
+			lengths[i] = columnLength
+		}
+	}
+
+	padRight := make([]bool, len(result.Types()))
+	for i, tpe := range result.Types() {
+		padRight[i] = !isNumeric(tpe)
+	}
+
+	for first := true; ; first = false {
+		data, err := result.Next()
+		if err != nil || data == nil {
+			return err
+		}
+
+		// pad the first row of each page so that the tables line up
+		for i, d := range data[0] {
+			if padRight[i] {
+				data[0][i] = tablewriter.PadRight(d, " ", lengths[i])
+			} else {
+				data[0][i] = tablewriter.PadLeft(d, " ", lengths[i])
+			}
+		}
+
+		table := tablewriter.NewWriter(out)
+		table.SetAutoFormatHeaders(false)
+		table.SetColWidth(72)
+		table.SetHeaderLine(true)
+		table.SetAutoWrapText(false)
+		table.SetReflowDuringAutoWrap(false)
+		table.SetBorders(tablewriter.Border{Left: false, Top: false, Right: false, Bottom: false})
+		table.SetCenterSeparator("|")
+		if first {
+			table.SetHeader(columns)
+		}
+		table.AppendBulk(data)
+		table.Render()
+	}
+}
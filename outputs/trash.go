@@ -1,19 +1,20 @@
 package outputs
 
 import (
+	"io"
+
 	"github.com/karlseguin/msql/driver"
 )
 
-func Trash(conn driver.Conn) (*driver.Meta, error) {
-	data, fin, err := conn.ReadFrame()
-	meta := driver.NewMeta(data)
-	for {
-		if err != nil {
-			return nil, err
-		}
-		if fin {
-			return meta, nil
-		}
-		_, fin, err = conn.ReadFrame()
-	}
+type trashFormatter struct {
+	NoOptions
+}
+
+func (_ trashFormatter) RenderReply(conn driver.Conn, out io.Writer, footer Footer) error {
+	return eachLine(conn, footer, func(line []byte) {})
+}
+
+// Unused, since RenderReply is implemented
+func (_ trashFormatter) Render(result driver.Result, out io.Writer) error {
+	return nil
 }
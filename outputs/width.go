@@ -0,0 +1,90 @@
+package outputs
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// Calls fn with each rune of s and the number of columns it takes in a
+// terminal. runewidth gives the width of individual runes, but terminals render
+// emoji sequences (like 👍🏽 or 👨‍👩‍👧) as a single emoji, so the skin tone
+// modifiers and the runes joined by a zero width joiner take no space.
+func eachRuneWidth(s string, fn func(r rune, width int)) {
+	joined := false
+	for _, r := range s {
+		width := 0
+		switch {
+		case r == 0x200D: // zero width joiner
+			joined = true
+		case r >= 0x1F3FB && r <= 0x1F3FF: // skin tone modifiers
+		case joined:
+			joined = false
+		default:
+			width = runewidth.RuneWidth(r)
+		}
+		fn(r, width)
+	}
+}
+
+func displayWidth(s string) int {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return len(s)
+	}
+
+	total := 0
+	eachRuneWidth(s, func(_ rune, width int) {
+		total += width
+	})
+	return total
+}
+
+// Breaks the line into lines no wider than width
+func wrap(line string, width int) []string {
+	if displayWidth(line) <= width {
+		return []string{line}
+	}
+
+	var lines []string
+	var sb strings.Builder
+	current := 0
+	eachRuneWidth(line, func(r rune, w int) {
+		if current+w > width && current > 0 {
+			lines = append(lines, sb.String())
+			sb.Reset()
+			current = 0
+		}
+		sb.WriteRune(r)
+		current += w
+	})
+	return append(lines, sb.String())
+}
+
+// Cuts the line to width, ending it with an ellipsis if anything was cut
+func truncate(line string, width int) string {
+	if displayWidth(line) <= width {
+		return line
+	}
+
+	var sb strings.Builder
+	current := 0
+	full := false
+	eachRuneWidth(line, func(r rune, w int) {
+		// the last column is for the ellipsis
+		if full || current+w > width-1 {
+			full = true
+			return
+		}
+		sb.WriteRune(r)
+		current += w
+	})
+	sb.WriteString("…")
+	return sb.String()
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+
+	"github.com/karlseguin/msql/outputs"
+	"github.com/mattn/go-runewidth"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const DEFAULT_PAGER = "less -S"
+
+// Sits between the output of a query and stdout. Holds on to the output until
+// it's taller or wider than the terminal, at which point the pager is started
+// and everything (what we've held on to and everything that follows) is piped
+// to it. Rows stream to the pager as they're received, only the first screen
+// is held in memory.
+type pager struct {
+	out     io.Writer
+	width   int
+	height  int
+	lines   int
+	buffer  bytes.Buffer
+	line    int // start of the current line in buffer
+	cmd     *exec.Cmd
+	pipe    io.WriteCloser
+	quit    bool
+	onQuit  func()
+	command string
+}
+
+// Returns nil when the pager shouldn't be used, either because it's off or
+// because stdout isn't a terminal
+func (c *Context) newPager() *pager {
+	mode := c.display.Pager
+	if mode == outputs.PAGER_OFF {
+		return nil
+	}
+
+	f, ok := c.out.(*os.File)
+	if !ok {
+		return nil
+	}
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return nil
+	}
+
+	command := os.Getenv("PAGER")
+	if command == "" {
+		command = DEFAULT_PAGER
+	}
+
+	p := &pager{
+		out:     c.out,
+		command: command,
+		width:   int(ws.Col),
+		// leave room for the prompt
+		height: int(ws.Row) - 1,
+	}
+	if mode == outputs.PAGER_ALWAYS {
+		p.start()
+	}
+	return p
+}
+
+func (p *pager) Write(data []byte) (int, error) {
+	if p.quit {
+		return len(data), nil
+	}
+	if p.pipe != nil {
+		if _, err := p.pipe.Write(data); err != nil {
+			// the pager was closed before we were done
+			p.quit = true
+			if p.onQuit != nil {
+				p.onQuit()
+			}
+		}
+		return len(data), nil
+	}
+
+	p.buffer.Write(data)
+	if p.overflows(data) {
+		p.start()
+	}
+	return len(data), nil
+}
+
+// Called for every write until the pager is started
+func (p *pager) overflows(data []byte) bool {
+	buffer := p.buffer.Bytes()
+	offset := len(buffer) - len(data)
+	for i, b := range data {
+		if b != '\n' {
+			continue
+		}
+		end := offset + i
+		if p.tooWide(buffer[p.line:end]) {
+			return true
+		}
+		p.line = end + 1
+		p.lines += 1
+		if p.lines > p.height {
+			return true
+		}
+	}
+	return p.tooWide(buffer[p.line:])
+}
+
+func (p *pager) tooWide(line []byte) bool {
+	// the display width is never more than the length in bytes
+	return len(line) > p.width && runewidth.StringWidth(string(line)) > p.width
+}
+
+func (p *pager) start() {
+	cmd := exec.Command("sh", "-c", p.command)
+	cmd.Stdout = p.out
+	cmd.Stderr = os.Stderr
+	pipe, err := cmd.StdinPipe()
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err != nil {
+		// no pager, we'll just write to stdout
+		log.WithFields(log.Fields{"context": "start pager", "pager": p.command}).Error(err)
+		p.pipe = nopCloser{p.out}
+	} else {
+		p.cmd = cmd
+		p.pipe = pipe
+	}
+
+	data := p.buffer.Bytes()
+	p.buffer = bytes.Buffer{}
+	p.Write(data)
+}
+
+// Writes whatever's been held on to (when the output fit on the screen) or
+// waits for the user to quit the pager
+func (p *pager) Close() {
+	if p.pipe == nil {
+		p.out.Write(p.buffer.Bytes())
+		return
+	}
+	p.pipe.Close()
+	if p.cmd != nil {
+		p.cmd.Wait()
+	}
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (_ nopCloser) Close() error { return nil }
@@ -7,14 +7,18 @@ import (
 	"strings"
 	"unicode"
 
+	"github.com/karlseguin/msql/outputs"
 	log "github.com/sirupsen/logrus"
 )
 
 type Preferences struct {
-	historyFile  string
-	passwordFile string
-	prompt       string
-	timing       bool
+	historyFile   string
+	passwordFile  string
+	prompt        string
+	timing        bool
+	autoReconnect bool
+	transferDir   string
+	display       outputs.Display
 }
 
 func loadPreferences() Preferences {
@@ -22,7 +26,7 @@ func loadPreferences() Preferences {
 	userConfigDir, err := os.UserConfigDir()
 	if err != nil {
 		log.WithFields(log.Fields{"context": "failed to load config dir"}).Error(err)
-		return Preferences{prompt: defaultPrompt}
+		return Preferences{prompt: defaultPrompt, autoReconnect: true, transferDir: "off", display: outputs.DefaultDisplay()}
 	}
 
 	configDir := path.Join(userConfigDir, "msql")
@@ -30,9 +34,12 @@ func loadPreferences() Preferences {
 	configFile := path.Join(configDir, "config")
 
 	preferences := Preferences{
-		historyFile:  path.Join(configDir, "history"),
-		passwordFile: path.Join(configDir, ".pass"),
-		prompt:       defaultPrompt,
+		historyFile:   path.Join(configDir, "history"),
+		passwordFile:  path.Join(configDir, ".pass"),
+		prompt:        defaultPrompt,
+		autoReconnect: true,
+		transferDir:   "off",
+		display:       outputs.DefaultDisplay(),
 	}
 
 	file, err := ioutil.ReadFile(configFile)
@@ -69,11 +76,24 @@ func loadPreferences() Preferences {
 			value = strings.ToLower(value)
 			preferences.timing = value == "on" || value == "1" || value == "true"
 			break
+		case "autoReconnect":
+			value = strings.ToLower(value)
+			preferences.autoReconnect = value == "on" || value == "1" || value == "true"
+			break
+		case "transferDir":
+			preferences.transferDir = value
+			break
 		case "prompt":
 			preferences.prompt = strings.Trim(value, "\"")
 			break
 		default:
-			log.WithFields(log.Fields{"context": configFile, "key": parts[0]}).Info("unknwon preference key")
+			// the \pset settings (null, border, pager, ...)
+			err := preferences.display.Set(parts[0], strings.Trim(value, "\""))
+			if err == outputs.ErrUnknownSetting {
+				log.WithFields(log.Fields{"context": configFile, "key": parts[0]}).Info("unknwon preference key")
+			} else if err != nil {
+				log.WithFields(log.Fields{"context": configFile, "key": parts[0], "value": value}).Error(err)
+			}
 		}
 	}
 
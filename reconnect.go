@@ -0,0 +1,141 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/karlseguin/msql/driver"
+	log "github.com/sirupsen/logrus"
+)
+
+// Called when a statement fails. If the failure is because the connection
+// dropped, we reconnect, tell the user whether the statement ran and offer to
+// re-run it (using rerun, unless it's nil). sent is whether the statement made
+// it to the server. Returns false if the error wasn't handled.
+func (c *Context) handleDisconnect(err error, sent bool, rerun func()) bool {
+	if !c.autoReconnect || !isDisconnect(err) {
+		return false
+	}
+
+	c.WriteString(fmt.Sprintf("connection lost (%s), reconnecting...\n", strings.TrimSpace(err.Error())))
+	if err := c.reconnect(); err != nil {
+		log.WithFields(log.Fields{"context": "reconnect"}).Fatal(err)
+	}
+
+	if sent {
+		c.WriteString("the statement was sent, but we don't know whether it ran\n")
+	} else {
+		c.WriteString("the statement did not run\n")
+	}
+
+	if rerun != nil && c.confirm("re-run it?") {
+		rerun()
+	}
+	return true
+}
+
+// Opens a new connection with our original config, retrying with a backoff,
+// and restores the session state.
+func (c *Context) reconnect() error {
+	var conn driver.Conn
+	delay := time.Millisecond * 250
+	for attempt := 1; ; attempt++ {
+		var err error
+		conn, err = driver.Open(c.config)
+		if err == nil {
+			break
+		}
+		if attempt == 8 {
+			return err
+		}
+		c.WriteString(fmt.Sprintf("reconnect failed (%s), retrying in %s\n", strings.TrimSpace(err.Error()), delay))
+		time.Sleep(delay)
+		delay *= 2
+	}
+
+	old := c.conn
+	old.Close()
+	c.conn = conn
+	c.WriteString("reconnected\n")
+
+	if row, err := conn.QueryRow("select current_sessionid()"); err == nil && len(row) == 1 {
+		fmt.Sscan(row[0], &c.sessionId)
+	}
+
+	// the schema and role might have been changed since we first connected
+	if row, err := conn.QueryRow("select current_schema, current_role"); err == nil && len(row) == 2 {
+		if row[0] != c.schema {
+			c.restore("set schema " + driver.QuoteIdentifier(c.schema))
+		}
+		if row[1] != c.role {
+			c.restore("set role " + driver.QuoteIdentifier(c.role))
+		}
+	}
+
+	if c.timeZone != "" {
+		c.restore(fmt.Sprintf("set time zone interval '%s' second", c.timeZone))
+	}
+
+	if !c.autoCommit {
+		if err := conn.SetAutoCommit(false); err != nil {
+			log.WithFields(log.Fields{"context": "reconnect: auto-commit"}).Error(err)
+		}
+		c.WriteString("auto-commit is off, any uncommitted changes were lost\n")
+	} else if !old.AutoCommit() {
+		c.WriteString("the open transaction was rolled back\n")
+	}
+
+	if len(c.prepared) > 0 {
+		c.WriteString("prepared statements were lost\n")
+		c.prepared = make(map[string]*driver.Stmt)
+	}
+	return nil
+}
+
+func (c *Context) restore(sql string) {
+	if _, err := c.conn.QueryRows(sql); err != nil {
+		log.WithFields(log.Fields{"context": "reconnect: restore", "sql": sql}).Error(err)
+	}
+}
+
+// Statements which change the session state we restore on reconnect. A false
+// positive only costs us an extra query.
+func changesSession(statement string) bool {
+	statement = strings.ToLower(statement)
+	return strings.Contains(statement, "set schema") || strings.Contains(statement, "set role") || strings.Contains(statement, "set time zone")
+}
+
+func (c *Context) refreshSession() {
+	row, err := c.conn.QueryRow("select current_schema, current_role, current_timezone")
+	if err != nil || len(row) != 3 {
+		log.WithFields(log.Fields{"context": "refresh session"}).Error(err)
+		return
+	}
+	c.schema = row[0]
+	c.role = row[1]
+	c.timeZone = row[2]
+}
+
+func isDisconnect(err error) bool {
+	driverErr, ok := err.(driver.Error)
+	return ok && driverErr.Source == driver.NETWORK_ERROR
+}
+
+// Asks through the line editor, which owns the terminal (reading stdin
+// directly would lose whatever it has buffered). Without one (e.g. a script)
+// the answer is no.
+func (c *Context) confirm(question string) bool {
+	if !c.interactive || c.editor == nil {
+		return false
+	}
+	c.editor.SetLeftPrompt(question + " [y/N] ")
+	answer, err := c.editor.GetLine()
+	if err != nil {
+		// Ctrl-C or Ctrl-D
+		c.WriteString("\n")
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
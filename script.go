@@ -0,0 +1,87 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/karlseguin/msql/lexer"
+	log "github.com/sirupsen/logrus"
+)
+
+// Runs the -c argument, the -f file or stdin (when it isn't a terminal) and
+// exits. Does nothing if there's none of these.
+func conditionallyExecuteAndExit(cArg string, fArg string, context *Context) {
+	var name, input string
+	if cArg != "" {
+		name = "-c"
+		input = strings.TrimSpace(cArg)
+	} else if fArg != "" {
+		data, err := ioutil.ReadFile(fArg)
+		if err != nil {
+			log.WithFields(log.Fields{"context": "fArg read"}).Fatal(err)
+		}
+		name = fArg
+		input = string(data)
+	} else {
+		fi, err := os.Stdin.Stat()
+		if err != nil {
+			log.WithFields(log.Fields{"context": "stat stdin"}).Error(err)
+		} else if fi.Mode()&os.ModeCharDevice == 0 {
+			// piped or redirected
+			data, err := ioutil.ReadAll(os.Stdin)
+			if err != nil {
+				log.WithFields(log.Fields{"context": "stdin read"}).Fatal(err)
+			}
+			name = "stdin"
+			input = string(data)
+		}
+	}
+
+	// no -c, -f or stdin
+	if input == "" {
+		return
+	}
+
+	runScript(context, name, input)
+	os.Exit(0)
+}
+
+// Statements and commands go through the same pipeline as when they're typed,
+// so a script can use \f, \timing, \o and the like. Anything logged while a
+// statement or command runs includes the script's name and the line the
+// statement started on.
+func runScript(context *Context, name string, input string) {
+	location := &scriptLocation{name: name}
+	log.AddHook(location)
+
+	first := true
+	for _, stmt := range lexer.Split(input) {
+		location.line = stmt.Line
+		if stmt.SQL == "" {
+			runCommand(context, stmt.Command)
+			continue
+		}
+		if !first {
+			context.WriteString("\n")
+		}
+		first = false
+		runStatement(context, stmt)
+	}
+}
+
+// A logrus hook
+type scriptLocation struct {
+	name string
+	line int
+}
+
+func (s *scriptLocation) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (s *scriptLocation) Fire(entry *log.Entry) error {
+	entry.Data["file"] = s.name
+	entry.Data["line"] = s.line
+	return nil
+}
@@ -0,0 +1,203 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	sqldriver "database/sql/driver"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+type Conn struct {
+	conn driver.Conn
+
+	// Set when an error leaves the connection in an unknown state (e.g. a
+	// read interrupted by a cancelled context). database/sql will discard it.
+	bad bool
+}
+
+func (c *Conn) Prepare(query string) (sqldriver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *Conn) PrepareContext(ctx context.Context, query string) (sqldriver.Stmt, error) {
+	var stmt *driver.Stmt
+	_, err := c.run(ctx, func() (driver.Result, error) {
+		var err error
+		stmt, err = c.conn.Prepare(query)
+		return nil, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Stmt{conn: c, stmt: stmt}, nil
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) Begin() (sqldriver.Tx, error) {
+	return c.BeginTx(context.Background(), sqldriver.TxOptions{})
+}
+
+// MonetDB's transactions are serializable, which is the only level we can
+// give (besides the default, which is the same)
+func (c *Conn) BeginTx(ctx context.Context, opts sqldriver.TxOptions) (sqldriver.Tx, error) {
+	level := sql.IsolationLevel(opts.Isolation)
+	if level != sql.LevelDefault && level != sql.LevelSerializable {
+		return nil, fmt.Errorf("isolation level %s is not supported, transactions are serializable", level)
+	}
+
+	start := "sstart transaction"
+	if opts.ReadOnly {
+		start += " read only"
+	}
+	if _, err := c.run(ctx, c.send(start, ";")); err != nil {
+		return nil, err
+	}
+	return Tx{conn: c}, nil
+}
+
+func (c *Conn) Ping(ctx context.Context) error {
+	_, err := c.run(ctx, c.send("sselect 1;"))
+	return err
+}
+
+func (c *Conn) IsValid() bool {
+	return !c.bad
+}
+
+func (c *Conn) ResetSession(ctx context.Context) error {
+	if c.bad {
+		return sqldriver.ErrBadConn
+	}
+	return nil
+}
+
+// Arguments require a server-side prepared statement, which database/sql will
+// do for us (via Prepare) when we return ErrSkip
+func (c *Conn) QueryContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	if len(args) > 0 {
+		return nil, sqldriver.ErrSkip
+	}
+	return c.query(ctx, c.send("s", query, ";"))
+}
+
+func (c *Conn) ExecContext(ctx context.Context, query string, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	if len(args) > 0 {
+		return nil, sqldriver.ErrSkip
+	}
+	return c.exec(ctx, c.send("s", query, ";"))
+}
+
+func (c *Conn) query(ctx context.Context, fn func() (driver.Result, error)) (sqldriver.Rows, error) {
+	done := c.watch(ctx)
+	result, err := c.do(fn)
+	if err != nil {
+		done()
+		return nil, c.fail(ctx, err)
+	}
+	return &Rows{conn: c, ctx: ctx, done: done, result: result}, nil
+}
+
+func (c *Conn) exec(ctx context.Context, fn func() (driver.Result, error)) (sqldriver.Result, error) {
+	result, err := c.run(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	affected := int64(0)
+	if meta := result.Meta(); meta != nil {
+		affected = int64(meta.RowCount)
+	}
+	return Result{affected: affected}, nil
+}
+
+// runs fn, which does I/O on the connection, honoring the context
+func (c *Conn) run(ctx context.Context, fn func() (driver.Result, error)) (driver.Result, error) {
+	done := c.watch(ctx)
+	defer done()
+	result, err := c.do(fn)
+	if err != nil {
+		return nil, c.fail(ctx, err)
+	}
+	return result, nil
+}
+
+func (c *Conn) do(fn func() (driver.Result, error)) (driver.Result, error) {
+	if c.bad {
+		return nil, sqldriver.ErrBadConn
+	}
+	result, err := fn()
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return driver.EmptyResult{}, nil
+	}
+	return result, nil
+}
+
+// sends the command and returns its first result
+func (c *Conn) send(parts ...string) func() (driver.Result, error) {
+	return func() (driver.Result, error) {
+		if err := c.conn.Send(parts...); err != nil {
+			return nil, err
+		}
+		return c.conn.NextResult()
+	}
+}
+
+// Our driver has no notion of a context, but it's built on a net.Conn, so we
+// can interrupt any blocked I/O by moving the deadline. Returns a function
+// which must be called once the I/O is done.
+func (c *Conn) watch(ctx context.Context) func() {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+	}
+
+	if ctx.Done() == nil {
+		return func() {}
+	}
+
+	stop := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		select {
+		case <-ctx.Done():
+			c.conn.SetDeadline(time.Unix(1, 0))
+		case <-stop:
+		}
+	}()
+
+	return func() {
+		close(stop)
+		<-finished
+		c.conn.SetDeadline(time.Time{})
+	}
+}
+
+// Errors from MonetDB leave the connection usable. Anything else (network
+// errors, interrupted reads) means we can't trust what's left on the socket.
+func (c *Conn) fail(ctx context.Context, err error) error {
+	if driverErr, ok := err.(driver.Error); ok && driverErr.Source == driver.MONETDB_ERROR {
+		return err
+	}
+
+	c.bad = true
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	// the socket's deadline (see watch) can pass before the context notices
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	if driverErr, ok := err.(driver.Error); ok && driverErr.Inner == io.EOF {
+		return sqldriver.ErrBadConn
+	}
+	return err
+}
@@ -0,0 +1,17 @@
+package sqldriver
+
+import (
+	"errors"
+)
+
+type Result struct {
+	affected int64
+}
+
+func (r Result) LastInsertId() (int64, error) {
+	return 0, errors.New("LastInsertId is not supported, use a returning clause")
+}
+
+func (r Result) RowsAffected() (int64, error) {
+	return r.affected, nil
+}
@@ -0,0 +1,109 @@
+package sqldriver
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+type Rows struct {
+	conn   *Conn
+	ctx    context.Context
+	done   func()
+	result driver.Result
+	next   driver.Result
+
+	// the rows returned by the last call to result.Next() which haven't been
+	// returned to database/sql yet
+	page [][]interface{}
+
+	// of reading the next result set, returned by NextResultSet
+	err error
+}
+
+func (r *Rows) Columns() []string {
+	return r.result.Columns()
+}
+
+func (r *Rows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.result.Types()[index]
+}
+
+func (r *Rows) Close() error {
+	if r.done != nil {
+		r.done()
+		r.done = nil
+	}
+	// Whatever we haven't read will be discarded by the next Send
+	return nil
+}
+
+func (r *Rows) Next(dest []sqldriver.Value) error {
+	for len(r.page) == 0 {
+		page, err := r.result.NextValues()
+		if err != nil {
+			return r.conn.fail(r.ctx, err)
+		}
+		if page == nil {
+			return io.EOF
+		}
+		r.page = page
+	}
+
+	row := r.page[0]
+	r.page = r.page[1:]
+	for i, value := range row {
+		dest[i] = driverValue(value)
+	}
+	return nil
+}
+
+// database/sql only deals with a handful of types, convert the few decoded
+// values that aren't one of them.
+func driverValue(value interface{}) sqldriver.Value {
+	switch v := value.(type) {
+	case *big.Int:
+		return v.String()
+	case driver.Decimal:
+		return v.String()
+	case time.Duration:
+		return int64(v)
+	}
+	return value
+}
+
+// A statement like "select 1; select 2;" returns multiple result sets. A
+// failure to read the next one is a result set too, so that NextResultSet
+// returns its error (rather than it looking like the end of the results).
+func (r *Rows) HasNextResultSet() bool {
+	if r.next != nil || r.err != nil {
+		return true
+	}
+	next, err := r.conn.conn.NextResult()
+	if err != nil {
+		r.err = r.conn.fail(r.ctx, err)
+		return true
+	}
+	if next == nil {
+		return false
+	}
+	r.next = next
+	return true
+}
+
+func (r *Rows) NextResultSet() error {
+	if !r.HasNextResultSet() {
+		return io.EOF
+	}
+	if r.err != nil {
+		return r.err
+	}
+	r.result = r.next
+	r.next = nil
+	r.page = nil
+	return nil
+}
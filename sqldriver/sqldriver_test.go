@@ -0,0 +1,304 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+// A reply ending with this is cut short: what comes before it is sent without
+// ending the message, then the connection is closed
+const testHangUp = "\x00hang up"
+
+// A server which accepts any login and answers each query with the reply of
+// replies. A query without one is never answered (as if it ran forever).
+// Returns the DSN to connect to it and the number of connections it accepted.
+func testServer(t *testing.T, replies map[string]string) (string, *int32) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted := new(int32)
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(accepted, 1)
+			go func() {
+				defer c.Close()
+				writeTestMessage(c, "salt:mserver:9:SHA512:LIT:SHA512:sql=6:")
+				if _, err := readTestMessage(c); err != nil {
+					return
+				}
+				writeTestMessage(c, "")
+				for {
+					query, err := readTestMessage(c)
+					if err != nil {
+						return
+					}
+					reply, ok := replies[query]
+					if !ok {
+						// wait for the client to give up and close the connection
+						io.Copy(ioutil.Discard, c)
+						return
+					}
+					if strings.HasSuffix(reply, testHangUp) {
+						reply = strings.TrimSuffix(reply, testHangUp)
+						header := make([]byte, 2)
+						binary.LittleEndian.PutUint16(header, uint16(len(reply)<<1))
+						c.Write(append(header, reply...))
+						return
+					}
+					writeTestMessage(c, reply)
+				}
+			}()
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return "monetdb://monetdb:monetdb@" + l.Addr().String() + "/db", accepted
+}
+
+func writeTestMessage(w io.Writer, message string) {
+	header := make([]byte, 2)
+	binary.LittleEndian.PutUint16(header, uint16(len(message)<<1|1))
+	w.Write(append(header, message...))
+}
+
+func readTestMessage(r io.Reader) (string, error) {
+	var message strings.Builder
+	header := make([]byte, 2)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return "", err
+		}
+		length := binary.LittleEndian.Uint16(header)
+		data := make([]byte, length>>1)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return "", err
+		}
+		message.Write(data)
+		if length&1 == 1 {
+			return message.String(), nil
+		}
+	}
+}
+
+func testOpen(t *testing.T, replies map[string]string) (*sql.DB, *int32) {
+	dsn, accepted := testServer(t, replies)
+	db, err := sql.Open("msql", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db, accepted
+}
+
+const testUsersReply = "&1 0 2 5 2 0 0 0 0\n" +
+	"% sys.users,\tsys.users,\tsys.users,\tsys.users,\tsys.users # table_name\n" +
+	"% id,\tname,\tbalance,\tcreated,\tnote # name\n" +
+	"% int,\tvarchar,\tdecimal,\ttimestamp,\tclob # type\n" +
+	"% 1,\t5,\t6,\t26,\t4 # length\n" +
+	"[ 1,\t\"leto\",\t100.25,\t2021-03-04 13:14:15.000000,\tNULL\t]\n" +
+	"[ 2,\t\"NULL\",\t-0.50,\t2021-03-05 00:00:00.500000,\t\"a\\tb\"\t]\n"
+
+func Test_Scan(t *testing.T) {
+	db, _ := testOpen(t, map[string]string{
+		"sselect * from users;": testUsersReply,
+	})
+
+	rows, err := db.Query("select * from users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"id", "name", "balance", "created", "note"}; !reflect.DeepEqual(columns, want) {
+		t.Errorf("columns:\n  got:  %#v\n  want: %#v", columns, want)
+	}
+
+	type user struct {
+		id      int
+		name    string
+		balance driver.Decimal
+		created time.Time
+		note    sql.NullString
+	}
+	var users []user
+	for rows.Next() {
+		var u user
+		if err := rows.Scan(&u.id, &u.name, &u.balance, &u.created, &u.note); err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, u)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []user{
+		{1, "leto", driver.Decimal{Unscaled: big.NewInt(10025), Scale: 2}, time.Date(2021, 3, 4, 13, 14, 15, 0, time.UTC), sql.NullString{}},
+		// the string 'NULL' isn't a NULL
+		{2, "NULL", driver.Decimal{Unscaled: big.NewInt(-50), Scale: 2}, time.Date(2021, 3, 5, 0, 0, 0, 500000000, time.UTC), sql.NullString{String: "a\tb", Valid: true}},
+	}
+	if !reflect.DeepEqual(users, want) {
+		t.Errorf("users:\n  got:  %#v\n  want: %#v", users, want)
+	}
+}
+
+func Test_Exec(t *testing.T) {
+	db, _ := testOpen(t, map[string]string{
+		"sdelete from users;": "&2 3 -1 0 0 0 0\n",
+	})
+
+	result, err := db.Exec("delete from users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if affected, _ := result.RowsAffected(); affected != 3 {
+		t.Errorf("expected 3 rows affected, got %d", affected)
+	}
+}
+
+func Test_Pooling(t *testing.T) {
+	db, accepted := testOpen(t, map[string]string{
+		"sselect * from users;": testUsersReply,
+		"sdelete from users;":   "&2 3 -1 0 0 0 0\n",
+	})
+	db.SetMaxOpenConns(1)
+
+	for i := 0; i < 5; i++ {
+		var id int
+		if err := db.QueryRow("select * from users").Scan(&id, new(string), new(string), new(time.Time), new(sql.NullString)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("delete from users"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := atomic.LoadInt32(accepted); n != 1 {
+		t.Errorf("expected the connection to be reused, %d were opened", n)
+	}
+}
+
+func Test_QueryContext_Deadline(t *testing.T) {
+	db, accepted := testOpen(t, map[string]string{
+		"sselect * from users;": testUsersReply,
+	})
+	db.SetMaxOpenConns(1)
+
+	// opens the connection, so that the deadline only applies to the query
+	if err := db.QueryRow("select * from users").Scan(new(int), new(string), new(string), new(time.Time), new(sql.NullString)); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := db.QueryContext(ctx, "select sleep(60000)")
+	if err != context.DeadlineExceeded {
+		t.Errorf("expected the deadline to be exceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected the query to be interrupted, it took %s", elapsed)
+	}
+
+	// the interrupted connection can't be trusted, so it's replaced
+	if err := db.QueryRow("select * from users").Scan(new(int), new(string), new(string), new(time.Time), new(sql.NullString)); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(accepted); n != 2 {
+		t.Errorf("expected the interrupted connection to be replaced, %d were opened", n)
+	}
+}
+
+func Test_NextResultSet(t *testing.T) {
+	db, accepted := testOpen(t, map[string]string{
+		"sselect * from users; select * from users;": testUsersReply + testUsersReply,
+		"sselect * from users; select x;":            testUsersReply + "!42000!SELECT: identifier 'x' unknown\n",
+		"sselect * from users; select 1;":            testUsersReply + "&1 1 1 1 1 0 0 0 0\n" + testHangUp,
+	})
+	db.SetMaxOpenConns(1)
+
+	count := func(query string) (int, error) {
+		rows, err := db.Query(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		sets := 0
+		for {
+			for rows.Next() {
+			}
+			sets += 1
+			if !rows.NextResultSet() {
+				return sets, rows.Err()
+			}
+		}
+	}
+
+	if sets, err := count("select * from users; select * from users"); sets != 2 || err != nil {
+		t.Errorf("expected 2 result sets, got %d (%v)", sets, err)
+	}
+
+	// the server's error, which leaves the connection usable
+	sets, err := count("select * from users; select x")
+	if err == nil || !strings.Contains(err.Error(), "identifier 'x' unknown") {
+		t.Errorf("expected the second statement's error, got %d result sets (%v)", sets, err)
+	}
+	if n := atomic.LoadInt32(accepted); n != 1 {
+		t.Errorf("expected the connection to be kept, %d were opened", n)
+	}
+
+	// the connection dropping in the middle of the reply, which leaves it bad
+	if sets, err := count("select * from users; select 1"); err == nil {
+		t.Errorf("expected the dropped connection's error, got %d result sets", sets)
+	}
+	if _, err := count("select * from users; select * from users"); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(accepted); n != 2 {
+		t.Errorf("expected the dropped connection to be replaced, %d were opened", n)
+	}
+}
+
+func Test_BeginTx_Isolation(t *testing.T) {
+	db, _ := testOpen(t, map[string]string{
+		"sstart transaction;": "&4 f\n",
+		"scommit;":            "&4 t\n",
+	})
+
+	for _, level := range []sql.IsolationLevel{sql.LevelDefault, sql.LevelSerializable} {
+		tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: level})
+		if err != nil {
+			t.Fatalf("%s: %v", level, err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("%s: %v", level, err)
+		}
+	}
+
+	for _, level := range []sql.IsolationLevel{sql.LevelReadUncommitted, sql.LevelReadCommitted, sql.LevelRepeatableRead, sql.LevelSnapshot, sql.LevelLinearizable} {
+		if tx, err := db.BeginTx(context.Background(), &sql.TxOptions{Isolation: level}); err == nil {
+			tx.Rollback()
+			t.Errorf("%s: expected an error", level)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package sqldriver
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+
+	"github.com/karlseguin/msql/driver"
+)
+
+// A server-side prepared statement
+type Stmt struct {
+	conn *Conn
+	stmt *driver.Stmt
+}
+
+func (s *Stmt) Close() error {
+	// don't bother if the connection is going to be thrown out
+	if s.conn.bad {
+		return nil
+	}
+	_, err := s.conn.run(context.Background(), func() (driver.Result, error) {
+		return nil, s.stmt.Close()
+	})
+	return err
+}
+
+func (s *Stmt) NumInput() int {
+	return len(s.stmt.Params())
+}
+
+func (s *Stmt) Exec(args []sqldriver.Value) (sqldriver.Result, error) {
+	return s.conn.exec(context.Background(), s.exec(values(args)))
+}
+
+func (s *Stmt) Query(args []sqldriver.Value) (sqldriver.Rows, error) {
+	return s.conn.query(context.Background(), s.exec(values(args)))
+}
+
+func (s *Stmt) ExecContext(ctx context.Context, args []sqldriver.NamedValue) (sqldriver.Result, error) {
+	return s.conn.exec(ctx, s.exec(namedValues(args)))
+}
+
+func (s *Stmt) QueryContext(ctx context.Context, args []sqldriver.NamedValue) (sqldriver.Rows, error) {
+	return s.conn.query(ctx, s.exec(namedValues(args)))
+}
+
+func (s *Stmt) exec(args []interface{}) func() (driver.Result, error) {
+	return func() (driver.Result, error) {
+		return s.stmt.Exec(args...)
+	}
+}
+
+func values(args []sqldriver.Value) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg
+	}
+	return values
+}
+
+func namedValues(args []sqldriver.NamedValue) []interface{} {
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return values
+}
@@ -0,0 +1,19 @@
+package sqldriver
+
+import (
+	"context"
+)
+
+type Tx struct {
+	conn *Conn
+}
+
+func (t Tx) Commit() error {
+	_, err := t.conn.run(context.Background(), t.conn.send("scommit;"))
+	return err
+}
+
+func (t Tx) Rollback() error {
+	_, err := t.conn.run(context.Background(), t.conn.send("srollback;"))
+	return err
+}
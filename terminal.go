@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// The width of the terminal, 0 if out isn't a terminal (e.g. it's piped)
+func terminalWidth(out interface{}) int {
+	if p, ok := out.(*pager); ok {
+		return p.width
+	}
+	f, ok := out.(*os.File)
+	if !ok {
+		return 0
+	}
+	ws, err := unix.IoctlGetWinsize(int(f.Fd()), unix.TIOCGWINSZ)
+	if err != nil {
+		return 0
+	}
+	return int(ws.Col)
+}